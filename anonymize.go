@@ -0,0 +1,184 @@
+// Copyright (c) 2015 Jesse Meek <https://github.com/waigani>
+// This program is Free Software see LICENSE file for details.
+
+package diffparser
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"path"
+	"strings"
+	"unicode"
+)
+
+// AnonymizeOptions controls how Anonymize hides the content of a diff.
+type AnonymizeOptions struct {
+	// HashContent replaces each line with a short hash of its content
+	// instead of a placeholder of the same length. Equal lines get equal
+	// hashes.
+	HashContent bool
+
+	// KeepWhitespace keeps whitespace characters in placeholders so the
+	// indentation and word shape of each line is preserved. It has no
+	// effect when HashContent is set.
+	KeepWhitespace bool
+
+	// HashNames replaces each element of the file names with a hash,
+	// keeping the directory structure and file extension.
+	HashNames bool
+}
+
+// Anonymize returns a copy of the diff with line content and hunk section
+// headings replaced so it can be shared without revealing code. The
+// structure of the diff (files, hunks, ranges, line modes and numbers) is
+// kept. The Raw text, the text before the first file, the parse warnings,
+// which quote lines, and binary patches are dropped from the copy, and so
+// are the header lines Parse does not model, but for mode and similarity
+// lines.
+func (d *Diff) Anonymize(opts AnonymizeOptions) *Diff {
+	c := d.clone()
+	c.Raw = ""
+	c.UnparsedPrefix = ""
+	c.ParseWarnings = nil
+
+	for _, f := range c.Files {
+		f.BinaryPatch = nil
+		var headers []string
+		for _, h := range f.ExtraHeaders {
+			if isStructuralHeader(h) {
+				headers = append(headers, h)
+			}
+		}
+		f.ExtraHeaders = headers
+
+		if opts.HashNames {
+			f.OrigName, f.NewName = hashPath(f.OrigName), hashPath(f.NewName)
+			f.DiffHeader = f.renamedHeader()
+		}
+
+		for _, h := range f.Chunks {
+			h.ChunkHeader = opts.anonymize(h.ChunkHeader)
+			// The raw header holds the heading too.
+			h.RawHeader = ""
+			// Lines shared between ranges must only be rewritten once.
+			done := make(map[*DiffLine]bool)
+			ranges := [][]*DiffLine{h.OrigRange.Lines, h.NewRange.Lines, h.WholeRange.Lines}
+			for _, r := range h.OrigRanges {
+				ranges = append(ranges, r.Lines)
+			}
+			for _, lines := range ranges {
+				for _, l := range lines {
+					if !done[l] {
+						l.Content = opts.anonymize(l.Content)
+						done[l] = true
+					}
+				}
+			}
+		}
+	}
+	return c
+}
+
+// renamedHeader returns the file's DiffHeader with the names in each of its
+// lines replaced by OrigName and NewName. Other text around the names, such
+// as the timestamps of a plain diff, is dropped with them.
+func (f *DiffFile) renamedHeader() string {
+	origName, newName := f.OrigName, f.NewName
+	switch f.Mode {
+	case New:
+		origName = newName
+	case Deleted:
+		newName = origName
+	}
+	// side returns the name of one side of a "---", "+++" or "***" line,
+	// keeping the prefix and "/dev/null" of the line it replaces.
+	side := func(line, name, prefix string) string {
+		old := fileLineName(line)
+		switch {
+		case old == "/dev/null":
+			return old
+		case strings.HasPrefix(unquoteName(old), prefix):
+			name = prefix + name
+		}
+		return quoteName(name)
+	}
+
+	lines := strings.Split(f.DiffHeader, "\n")
+	// A context diff's "---" line names the new side.
+	context := strings.HasPrefix(lines[0], "*** ")
+	for i, l := range lines {
+		switch {
+		case strings.HasPrefix(l, "diff --git "):
+			l = "diff --git " + quoteName("a/"+origName) + " " + quoteName("b/"+newName)
+		case strings.HasPrefix(l, "diff --cc "):
+			l = "diff --cc " + quoteName(f.name())
+		case strings.HasPrefix(l, "diff --combined "):
+			l = "diff --combined " + quoteName(f.name())
+		case strings.HasPrefix(l, "Index: "):
+			l = "Index: " + f.name()
+		case strings.HasPrefix(l, "*** "), strings.HasPrefix(l, "--- ") && !context:
+			l = l[:4] + side(l[4:], f.OrigName, "a/")
+		case strings.HasPrefix(l, "+++ "), strings.HasPrefix(l, "--- "):
+			l = l[:4] + side(l[4:], f.NewName, "b/")
+		case strings.HasPrefix(l, "rename from "), strings.HasPrefix(l, "copy from "):
+			// The first line of a bare header.
+			l = l[:strings.Index(l, " from ")+len(" from ")] + quoteName(f.OrigName)
+		case strings.HasPrefix(l, "rename to "), strings.HasPrefix(l, "copy to "):
+			l = l[:strings.Index(l, " to ")+len(" to ")] + quoteName(f.NewName)
+		}
+		lines[i] = l
+	}
+	return strings.Join(lines, "\n")
+}
+
+// isStructuralHeader reports whether h, an extra header line of a file,
+// holds only a file mode or similarity score, and no names or content.
+func isStructuralHeader(h string) bool {
+	for _, prefix := range []string{"new file mode ", "deleted file mode ", "similarity index ", "dissimilarity index "} {
+		if strings.HasPrefix(h, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// anonymize returns the placeholder or hash for s.
+func (opts AnonymizeOptions) anonymize(s string) string {
+	if s == "" {
+		return ""
+	}
+	if opts.HashContent {
+		return shortHash(s)
+	}
+	return strings.Map(func(r rune) rune {
+		if opts.KeepWhitespace && unicode.IsSpace(r) {
+			return r
+		}
+		return 'x'
+	}, s)
+}
+
+// hashPath hashes each element of the slash separated path p, keeping the
+// extension of the last element.
+func hashPath(p string) string {
+	if p == "" {
+		return ""
+	}
+	elems := strings.Split(p, "/")
+	for i, e := range elems {
+		if e == "" {
+			continue
+		}
+		ext := ""
+		if i == len(elems)-1 {
+			ext = path.Ext(e)
+		}
+		elems[i] = shortHash(strings.TrimSuffix(e, ext)) + ext
+	}
+	return strings.Join(elems, "/")
+}
+
+func shortHash(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:6])
+}
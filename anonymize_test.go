@@ -0,0 +1,144 @@
+// Copyright (c) 2015 Jesse Meek <https://github.com/waigani>
+// This program is Free Software see LICENSE file for details.
+
+package diffparser
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnonymize(t *testing.T) {
+	diff := setup(t)
+
+	anon := diff.Anonymize(AnonymizeOptions{KeepWhitespace: true})
+	require.Empty(t, anon.Raw)
+	require.Len(t, anon.Files, len(diff.Files))
+
+	file := anon.Files[0]
+	require.Equal(t, "file1", file.NewName)
+	lines := file.Chunks[0].WholeRange.Lines
+	require.Equal(t, "xxx x xxxx", lines[0].Content)
+	require.Equal(t, Added, lines[0].Mode)
+	require.Equal(t, 1, lines[0].Number)
+	require.Equal(t, "xxxx", file.Chunks[0].OrigRange.Lines[0].Content)
+
+	// The original is left untouched.
+	require.Equal(t, "add a line", diff.Files[0].Chunks[0].WholeRange.Lines[0].Content)
+	require.NotEmpty(t, diff.Raw)
+
+	anon = diff.Anonymize(AnonymizeOptions{})
+	require.Equal(t, "xxxxxxxxxx", anon.Files[0].Chunks[0].WholeRange.Lines[0].Content)
+}
+
+func TestAnonymizeHashes(t *testing.T) {
+	diff, err := Parse(`diff --git a/src/secret.go b/src/secret.go
+index 504d2a1..50ccec3 100644
+--- a/src/secret.go
++++ b/src/secret.go
+@@ -1,2 +1,2 @@ func Secret()
+-password := "hunter2"
++password := "hunter2"
+ return password
+`)
+	require.NoError(t, err)
+
+	anon := diff.Anonymize(AnonymizeOptions{HashContent: true, HashNames: true})
+	file := anon.Files[0]
+	require.NotContains(t, file.NewName, "secret")
+	require.True(t, strings.HasPrefix(file.NewName, shortHash("src")+"/"))
+	require.True(t, strings.HasSuffix(file.NewName, ".go"))
+	require.Equal(t, file.NewName, file.OrigName)
+	require.NotContains(t, file.DiffHeader, "secret")
+	require.NotContains(t, file.Chunks[0].ChunkHeader, "Secret")
+
+	lines := file.Chunks[0].WholeRange.Lines
+	require.Equal(t, lines[0].Content, lines[1].Content)
+	require.NotContains(t, lines[0].Content, "hunter2")
+}
+
+func TestAnonymizeHeaders(t *testing.T) {
+	diff, err := ParseWithOptions(`SECRET_TOKEN=abc123
+diff --git a/file1 b/file1
+new file mode 100644
+x-secret-header abc123
+index 0000000..50ccec3
+--- /dev/null
++++ b/file1
+@@ -0,0 +1,2 @@
++one
+=20 abc123
++two
+diff --git a/icon.png b/icon.png
+index 504d2a1..50ccec3 100644
+GIT binary patch
+literal 12
+TcmZ?wbhEHbRA6LaU|;|M1^@*B
+
+literal 10
+RcmZ?wbhEHbRA6La00001
+
+`, ParseOptions{SkipBadLines: true})
+	require.NoError(t, err)
+	require.NotEmpty(t, diff.UnparsedPrefix)
+	require.NotEmpty(t, diff.ParseWarnings)
+
+	anon := diff.Anonymize(AnonymizeOptions{})
+	require.Empty(t, anon.UnparsedPrefix)
+	require.Empty(t, anon.ParseWarnings)
+	require.Equal(t, []string{"new file mode 100644"}, anon.Files[0].ExtraHeaders)
+	require.Nil(t, anon.Files[1].BinaryPatch)
+	require.True(t, anon.Files[1].IsBinary)
+	require.NotContains(t, anon.String(), "abc123")
+	require.NotContains(t, anon.String(), "TcmZ")
+
+	// The original is left untouched.
+	require.Equal(t, "SECRET_TOKEN=abc123\n", diff.UnparsedPrefix)
+	require.Equal(t, []string{"new file mode 100644", "x-secret-header abc123"}, diff.Files[0].ExtraHeaders)
+}
+
+func TestAnonymizeHashesHeader(t *testing.T) {
+	diff, err := Parse(`diff --git a/a b/a
+index 504d2a1..50ccec3 100644
+--- a/a
++++ b/a
+@@ -1 +1 @@
+-one
++uno
+diff --git a/b b/b
+index 0000000..50ccec3
+--- /dev/null
++++ b/b
+@@ -0,0 +1 @@
++new
+`)
+	require.NoError(t, err)
+	require.Len(t, diff.Files, 2)
+
+	anon := diff.Anonymize(AnonymizeOptions{HashNames: true})
+	a, b := hashPath("a"), hashPath("b")
+	require.Equal(t, "diff --git a/"+a+" b/"+a+"\nindex 504d2a1..50ccec3 100644\n--- a/"+a+"\n+++ b/"+a,
+		anon.Files[0].DiffHeader)
+	require.Equal(t, "diff --git a/"+b+" b/"+b+"\nindex 0000000..50ccec3\n--- /dev/null\n+++ b/"+b,
+		anon.Files[1].DiffHeader)
+
+	again, err := Parse(anon.String())
+	require.NoError(t, err)
+	require.Equal(t, a, again.Files[0].NewName)
+	require.Equal(t, New, again.Files[1].Mode)
+	require.Equal(t, b, again.Files[1].NewName)
+
+	// A plain diff's file lines lose their timestamps.
+	diff, err = Parse(`--- dir/c.txt	2020-01-01 00:00:00.000000000 +0000
++++ dir/c.txt	2020-01-02 00:00:00.000000000 +0000
+@@ -1 +1 @@
+-one
++uno
+`)
+	require.NoError(t, err)
+	anon = diff.Anonymize(AnonymizeOptions{HashNames: true})
+	c := hashPath("dir/c.txt")
+	require.Equal(t, "--- "+c+"\n+++ "+c, anon.Files[0].DiffHeader)
+}
@@ -0,0 +1,152 @@
+// Copyright (c) 2015 Jesse Meek <https://github.com/waigani>
+// This program is Free Software see LICENSE file for details.
+
+package diffparser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ApplyTo applies every file of the diff to files, a map of path to
+// content, and returns the resulting map; files is not modified. Modified
+// files are patched, New files are created, Deleted files are removed and
+// Renamed files are patched and moved to their new name. Copied files are
+// patched into their new name, keeping the original.
+// It returns an error if a hunk doesn't match, a file to patch or delete is
+// missing, a file to create or the target of a rename or copy already
+// exists, or a binary file is changed, as its content is not parsed.
+// Binary files can still be deleted.
+func (d *Diff) ApplyTo(files map[string]string) (map[string]string, error) {
+	result := make(map[string]string, len(files))
+	for name, content := range files {
+		result[name] = content
+	}
+
+	for _, f := range d.Files {
+		var content string
+		if f.Mode == New {
+			if _, ok := result[f.NewName]; ok {
+				return nil, fmt.Errorf("new file %q already exists", f.NewName)
+			}
+		} else {
+			var ok bool
+			if content, ok = result[f.OrigName]; !ok {
+				return nil, fmt.Errorf("file %q does not exist", f.OrigName)
+			}
+		}
+		if f.Mode == Renamed || f.Mode == Copied {
+			if _, ok := result[f.NewName]; ok && f.NewName != f.OrigName {
+				return nil, fmt.Errorf("%s file %q already exists", strings.ToLower(f.Mode.String()), f.NewName)
+			}
+		}
+
+		if f.Mode != Deleted || !f.IsBinary {
+			var err error
+			if content, err = f.applyHunks(content, false); err != nil {
+				return nil, err
+			}
+		}
+
+		switch f.Mode {
+		case Deleted:
+			delete(result, f.OrigName)
+		case New, Copied:
+			result[f.NewName] = content
+		default:
+			delete(result, f.OrigName)
+			result[f.NewName] = content
+		}
+	}
+	return result, nil
+}
+
+// Apply applies the file's change to orig, the content of the file before
+// it, and returns the content after it. It returns an error if a hunk's
+// context or removed lines don't match orig, as when the diff is stale, or
+// if the file is binary.
+func (f *DiffFile) Apply(orig string) (string, error) {
+	return f.applyHunks(orig, false)
+}
+
+// ApplyReverse undoes the file's change: given the content of the file
+// after the change it returns the content before it. It returns an error if
+// a hunk's context or added lines don't match newContent.
+func (f *DiffFile) ApplyReverse(newContent string) (string, error) {
+	return f.applyHunks(newContent, true)
+}
+
+// applyHunks applies the file's hunks to content, or undoes them if reverse
+// is set. The hunks are matched at the exact lines their ranges declare.
+// A binary file has no hunks to apply, which is an error.
+func (f *DiffFile) applyHunks(content string, reverse bool) (string, error) {
+	if f.IsBinary && len(f.Chunks) == 0 {
+		return "", fmt.Errorf("cannot apply binary file %q", f.name())
+	}
+	src := strings.SplitAfter(content, "\n")
+	if src[len(src)-1] == "" {
+		src = src[:len(src)-1]
+	}
+	// Lines are compared and emitted without their terminators. The last
+	// line of the result is terminated as the hunk ending the file marks
+	// it or, if no hunk does, as that of content was.
+	terminated := content == "" || strings.HasSuffix(content, "\n")
+	for i, l := range src {
+		src[i] = strings.TrimSuffix(l, "\n")
+	}
+
+	var out []string
+	var pos int
+	var outLast *DiffLine // the last line of out, if it is from a hunk
+	for i, h := range f.Chunks {
+		srcRange, srcMode, outMode := h.OrigRange, Removed, Added
+		if reverse {
+			srcRange, srcMode, outMode = h.NewRange, Added, Removed
+		}
+
+		// A range of length zero starts after the line it names.
+		start := srcRange.Start - 1
+		if srcRange.Length == 0 {
+			start = srcRange.Start
+		}
+		if start < pos || start > len(src) {
+			return "", fmt.Errorf("hunk %d of %q: line %d is out of range", i+1, f.name(), srcRange.Start)
+		}
+		if start > pos {
+			out = append(out, src[pos:start]...)
+			outLast = nil
+		}
+		pos = start
+
+		for _, l := range h.WholeRange.Lines {
+			if l.Mode == Unchanged || l.Mode == srcMode {
+				if pos >= len(src) || src[pos] != l.text() {
+					return "", fmt.Errorf("hunk %d of %q: line %d does not match %q", i+1, f.name(), pos+1, l.text())
+				}
+				pos++
+			}
+			if l.Mode == Unchanged || l.Mode == outMode {
+				out = append(out, l.text())
+				outLast = l
+			}
+		}
+	}
+	if pos < len(src) {
+		out = append(out, src[pos:]...)
+	} else if outLast != nil {
+		terminated = !outLast.NoNewline
+	} else {
+		// The last hunk removed the end of the file, so the line before
+		// it, now last, was terminated.
+		terminated = true
+	}
+
+	if len(out) == 0 {
+		return "", nil
+	}
+	result := strings.Join(out, "\n")
+	if terminated {
+		result += "\n"
+	}
+	return result, nil
+}
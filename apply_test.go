@@ -0,0 +1,153 @@
+// Copyright (c) 2015 Jesse Meek <https://github.com/waigani>
+// This program is Free Software see LICENSE file for details.
+
+package diffparser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestApply(t *testing.T) {
+	diff, err := Parse(multiHunkDiff)
+	require.NoError(t, err)
+
+	content, err := diff.Files[0].Apply(multiHunkOrig)
+	require.NoError(t, err)
+	require.Equal(t, "uno\ntwo\nthree\nfour\nfive\nsix\nseven\neight\nnueve\nten\n", content)
+
+	_, err = diff.Files[0].Apply("one\nzwei\nthree\n")
+	require.EqualError(t, err, `hunk 1 of "file1": line 2 does not match "two"`)
+
+	// The last line of the file is changed, and is unterminated on both
+	// sides.
+	diff, err = Parse(`diff --git a/file1 b/file1
+index 504d2a1..50ccec3 100644
+--- a/file1
++++ b/file1
+@@ -1,2 +1,2 @@
+ one
+-two
+\ No newline at end of file
++dos
+\ No newline at end of file
+`)
+	require.NoError(t, err)
+	content, err = diff.Files[0].Apply("one\ntwo")
+	require.NoError(t, err)
+	require.Equal(t, "one\ndos", content)
+
+	// The diff adds the missing newline.
+	diff, err = Parse(noNewlineDiff)
+	require.NoError(t, err)
+	content, err = diff.Files[0].Apply("one\ntwo\nthree")
+	require.NoError(t, err)
+	require.Equal(t, "one\ndos\ntres\n", content)
+	orig, err := diff.Files[0].ApplyReverse(content)
+	require.NoError(t, err)
+	require.Equal(t, "one\ntwo\nthree", orig)
+}
+
+func TestApplyReverse(t *testing.T) {
+	diff, err := Parse(multiHunkDiff)
+	require.NoError(t, err)
+
+	newContent := "uno\ntwo\nthree\nfour\nfive\nsix\nseven\neight\nnueve\nten\n"
+	orig, err := diff.Files[0].ApplyReverse(newContent)
+	require.NoError(t, err)
+	require.Equal(t, multiHunkOrig, orig)
+
+	_, err = diff.Files[0].ApplyReverse(multiHunkOrig)
+	require.EqualError(t, err, `hunk 1 of "file1": line 1 does not match "uno"`)
+
+	_, err = diff.Files[0].ApplyReverse("uno\ntwo\nthree\nfour\n")
+	require.EqualError(t, err, `hunk 2 of "file1": line 7 is out of range`)
+}
+
+func TestApplyReverseNewAndDeleted(t *testing.T) {
+	diff := setup(t)
+
+	// Reverting a new file empties it.
+	orig, err := diff.Files[4].ApplyReverse("other\nlines\nin\nfile2\n")
+	require.NoError(t, err)
+	require.Equal(t, "", orig)
+
+	// Reverting a deletion restores the file.
+	orig, err = diff.Files[1].ApplyReverse("")
+	require.NoError(t, err)
+	require.Equal(t, "other\nlines\nin\nfile2\n", orig)
+}
+
+func TestApplyTo(t *testing.T) {
+	diff := setup(t)
+	files := map[string]string{
+		"file1":   "some\nlines\nin\nfile1\n",
+		"file2":   "other\nlines\nin\nfile2\n",
+		"file3":   "still\nmore\nin\nfile3\n",
+		"symlink": "symlink-destination\n",
+		"other":   "untouched\n",
+	}
+
+	result, err := diff.ApplyTo(files)
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{
+		"file1":   "add a line\nsome\nlines\nfile1\n",
+		"file4":   "added new file",
+		"newname": "other\nlines\nin\nfile2\n",
+		"other":   "untouched\n",
+	}, result)
+	require.Len(t, files, 5)
+
+	delete(files, "file2")
+	_, err = diff.ApplyTo(files)
+	require.EqualError(t, err, `file "file2" does not exist`)
+
+	files["file2"] = "other\nlines\nin\nfile2\n"
+	files["file4"] = ""
+	_, err = diff.ApplyTo(files)
+	require.EqualError(t, err, `new file "file4" already exists`)
+
+	delete(files, "file4")
+	files["file1"] = "changed\n"
+	_, err = diff.ApplyTo(files)
+	require.EqualError(t, err, `hunk 1 of "file1": line 1 does not match "some"`)
+
+	moves, err := Parse(`diff --git a/a b/b
+similarity index 100%
+rename from a
+rename to b
+diff --git a/c b/d
+similarity index 100%
+copy from c
+copy to d
+`)
+	require.NoError(t, err)
+	_, err = moves.ApplyTo(map[string]string{"a": "A\n", "b": "B\n", "c": "C\n"})
+	require.EqualError(t, err, `renamed file "b" already exists`)
+	_, err = moves.ApplyTo(map[string]string{"a": "A\n", "c": "C\n", "d": "D\n"})
+	require.EqualError(t, err, `copied file "d" already exists`)
+	result, err = moves.ApplyTo(map[string]string{"a": "A\n", "c": "C\n"})
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"b": "A\n", "c": "C\n", "d": "C\n"}, result)
+
+	binary, err := Parse(`diff --git a/logo.png b/logo.png
+index 504d2a1..50ccec3 100644
+Binary files a/logo.png and b/logo.png differ
+`)
+	require.NoError(t, err)
+	_, err = binary.ApplyTo(map[string]string{"logo.png": "\x89PNG"})
+	require.EqualError(t, err, `cannot apply binary file "logo.png"`)
+	_, err = binary.Files[0].Apply("\x89PNG")
+	require.EqualError(t, err, `cannot apply binary file "logo.png"`)
+
+	binary, err = Parse(`diff --git a/logo.png b/logo.png
+deleted file mode 100644
+index 504d2a1..0000000
+Binary files a/logo.png and /dev/null differ
+`)
+	require.NoError(t, err)
+	result, err = binary.ApplyTo(map[string]string{"logo.png": "\x89PNG"})
+	require.NoError(t, err)
+	require.Empty(t, result)
+}
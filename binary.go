@@ -0,0 +1,68 @@
+// Copyright (c) 2015 Jesse Meek <https://github.com/waigani>
+// This program is Free Software see LICENSE file for details.
+
+package diffparser
+
+import (
+	"bytes"
+	"encoding/gob"
+)
+
+// diffData has the fields of Diff without its methods, so gob encodes it
+// field by field rather than calling MarshalBinary.
+type diffData Diff
+
+// MarshalBinary implements encoding.BinaryMarshaler using gob. It is more
+// compact and faster to decode than JSON, which makes it suited to caching
+// parsed diffs.
+func (d *Diff) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode((*diffData)(d)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler, decoding data
+// written by MarshalBinary.
+func (d *Diff) UnmarshalBinary(data []byte) error {
+	var dd diffData
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&dd); err != nil {
+		return err
+	}
+	*d = Diff(dd)
+	for _, f := range d.Files {
+		for _, h := range f.Chunks {
+			h.relinkLines()
+		}
+	}
+	return nil
+}
+
+// relinkLines makes the lines of WholeRange the same values as those in
+// OrigRange and NewRange, as Parse builds them. Gob decodes each reference
+// to a line as a separate copy.
+func (hunk *DiffChunk) relinkLines() {
+	if hunk.OrigRanges != nil {
+		// A parent's lines can't be told from the others' by mode.
+		return
+	}
+	orig, new := hunk.OrigRange.Lines, hunk.NewRange.Lines
+	var oi, ni int
+	for i, l := range hunk.WholeRange.Lines {
+		switch {
+		case l.Mode == Removed && oi < len(orig):
+			hunk.WholeRange.Lines[i] = orig[oi]
+			oi++
+		case l.Mode == Added && ni < len(new):
+			hunk.WholeRange.Lines[i] = new[ni]
+			ni++
+		case l.Mode == Unchanged && oi < len(orig) && ni < len(new):
+			hunk.WholeRange.Lines[i] = new[ni]
+			oi++
+			ni++
+		default:
+			return
+		}
+	}
+}
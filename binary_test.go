@@ -0,0 +1,118 @@
+// Copyright (c) 2015 Jesse Meek <https://github.com/waigani>
+// This program is Free Software see LICENSE file for details.
+
+package diffparser
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// largeDiff returns a diff of files files with hunks hunks each.
+func largeDiff(files, hunks int) string {
+	var b strings.Builder
+	for f := 0; f < files; f++ {
+		fmt.Fprintf(&b, "diff --git a/dir/file%d.go b/dir/file%d.go\n", f, f)
+		b.WriteString("index 504d2a1..50ccec3 100644\n")
+		fmt.Fprintf(&b, "--- a/dir/file%d.go\n+++ b/dir/file%d.go\n", f, f)
+		for h := 0; h < hunks; h++ {
+			start := h*10 + 1
+			fmt.Fprintf(&b, "@@ -%d,7 +%d,7 @@ func f%d() {\n", start, start, h)
+			b.WriteString(" \tctx := context.Background()\n \tclient := newClient(ctx)\n \tdefer client.Close()\n")
+			b.WriteString("-\tresult, err := client.Do(request)\n+\tresult, err := client.DoWithRetry(ctx, request)\n")
+			b.WriteString(" \tif err != nil {\n \t\treturn err\n \t}\n")
+		}
+	}
+	return b.String()
+}
+
+func TestMarshalBinary(t *testing.T) {
+	diff := setup(t)
+	diff.PullID = 42
+
+	data, err := diff.MarshalBinary()
+	require.NoError(t, err)
+
+	var got Diff
+	require.NoError(t, got.UnmarshalBinary(data))
+	require.Equal(t, diff, &got)
+
+	// Lines are shared between ranges as after Parse.
+	chunk := got.Files[0].Chunks[0]
+	require.True(t, chunk.WholeRange.Lines[0] == chunk.NewRange.Lines[0])
+	require.True(t, chunk.WholeRange.Lines[3] == chunk.OrigRange.Lines[2])
+
+	require.Error(t, got.UnmarshalBinary([]byte("garbage")))
+
+	// Summary counts are kept, in JSON too.
+	numstat, err := ParseNumstat("3\t4\tfile1\n")
+	require.NoError(t, err)
+	require.Equal(t, Stats{1, 3, 4}, numstat.Stats())
+	data, err = numstat.MarshalBinary()
+	require.NoError(t, err)
+	got = Diff{}
+	require.NoError(t, got.UnmarshalBinary(data))
+	require.Equal(t, numstat.Stats(), got.Stats())
+
+	data, err = json.Marshal(numstat)
+	require.NoError(t, err)
+	got = Diff{}
+	require.NoError(t, json.Unmarshal(data, &got))
+	require.Equal(t, numstat.Stats(), got.Stats())
+}
+
+func BenchmarkMarshalBinary(b *testing.B) {
+	diff, err := Parse(largeDiff(50, 20))
+	require.NoError(b, err)
+	diff.Raw = ""
+
+	var data []byte
+	for i := 0; i < b.N; i++ {
+		data, err = diff.MarshalBinary()
+		require.NoError(b, err)
+	}
+	b.Logf("%d bytes", len(data))
+}
+
+func BenchmarkMarshalJSON(b *testing.B) {
+	diff, err := Parse(largeDiff(50, 20))
+	require.NoError(b, err)
+	diff.Raw = ""
+
+	var data []byte
+	for i := 0; i < b.N; i++ {
+		data, err = json.Marshal(diff)
+		require.NoError(b, err)
+	}
+	b.Logf("%d bytes", len(data))
+}
+
+func BenchmarkUnmarshalBinary(b *testing.B) {
+	diff, err := Parse(largeDiff(50, 20))
+	require.NoError(b, err)
+	diff.Raw = ""
+	data, err := diff.MarshalBinary()
+	require.NoError(b, err)
+
+	for i := 0; i < b.N; i++ {
+		var d Diff
+		require.NoError(b, d.UnmarshalBinary(data))
+	}
+}
+
+func BenchmarkUnmarshalJSON(b *testing.B) {
+	diff, err := Parse(largeDiff(50, 20))
+	require.NoError(b, err)
+	diff.Raw = ""
+	data, err := json.Marshal(diff)
+	require.NoError(b, err)
+
+	for i := 0; i < b.N; i++ {
+		var d Diff
+		require.NoError(b, json.Unmarshal(data, &d))
+	}
+}
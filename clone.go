@@ -0,0 +1,58 @@
+// Copyright (c) 2015 Jesse Meek <https://github.com/waigani>
+// This program is Free Software see LICENSE file for details.
+
+package diffparser
+
+// clone returns a deep copy of the diff. Lines shared between a chunk's
+// ranges stay shared in the copy.
+func (d *Diff) clone() *Diff {
+	c := *d
+	c.Files = make([]*DiffFile, len(d.Files))
+	for i, f := range d.Files {
+		c.Files[i] = f.clone()
+	}
+	return &c
+}
+
+// clone returns a deep copy of the file.
+func (f *DiffFile) clone() *DiffFile {
+	c := *f
+	c.Chunks = make([]*DiffChunk, len(f.Chunks))
+	for i, h := range f.Chunks {
+		c.Chunks[i] = h.clone()
+	}
+	return &c
+}
+
+// clone returns a deep copy of the chunk.
+func (hunk *DiffChunk) clone() *DiffChunk {
+	c := *hunk
+	copies := make(map[*DiffLine]*DiffLine)
+	cloneLines := func(lines []*DiffLine) []*DiffLine {
+		if lines == nil {
+			return nil
+		}
+		cls := make([]*DiffLine, len(lines))
+		for i, l := range lines {
+			cl, ok := copies[l]
+			if !ok {
+				v := *l
+				cl = &v
+				copies[l] = cl
+			}
+			cls[i] = cl
+		}
+		return cls
+	}
+	c.OrigRange.Lines = cloneLines(hunk.OrigRange.Lines)
+	c.NewRange.Lines = cloneLines(hunk.NewRange.Lines)
+	c.WholeRange.Lines = cloneLines(hunk.WholeRange.Lines)
+	if hunk.OrigRanges != nil {
+		c.OrigRanges = make([]DiffRange, len(hunk.OrigRanges))
+		for i, r := range hunk.OrigRanges {
+			c.OrigRanges[i] = r
+			c.OrigRanges[i].Lines = cloneLines(r.Lines)
+		}
+	}
+	return &c
+}
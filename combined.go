@@ -0,0 +1,170 @@
+// Copyright (c) 2015 Jesse Meek <https://github.com/waigani>
+// This program is Free Software see LICENSE file for details.
+
+package diffparser
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// startCombinedHunk parses the "@@@ -<start>,<length> -<start>,<length>
+// +<start>,<length> @@@" header of a combined diff hunk, as git prints for
+// merges, into hunk. There is one "-" range and one more "@" than "@@" for
+// each parent.
+func (p *parser) startCombinedHunk(hunk *DiffChunk, l string) error {
+	i := strings.IndexByte(l, ' ')
+	if i < 0 {
+		return p.errorf(l, "could not parse hunk header")
+	}
+	marker := l[:i]
+	fields := strings.Fields(l[len(marker):])
+	end := -1
+	for i, f := range fields {
+		if f == marker {
+			end = i
+			break
+		}
+	}
+	if end != len(marker) {
+		return p.errorf(l, "could not parse hunk header")
+	}
+
+	var ranges []DiffRange
+	for i, f := range fields[:end] {
+		sign := "-"
+		if i == end-1 {
+			sign = "+"
+		}
+		if !strings.HasPrefix(f, sign) {
+			return p.errorf(l, "could not parse hunk header")
+		}
+		r, err := parseRange(f[1:])
+		if err != nil {
+			return p.errorf(l, "could not parse hunk header")
+		}
+		if int64(r.Start)+int64(r.Length) > math.MaxInt32 {
+			return p.errorf(l, "hunk line numbers out of range")
+		}
+		ranges = append(ranges, r)
+	}
+	if i := strings.Index(l, " "+marker+" "); i >= 0 {
+		hunk.ChunkHeader = l[i+len(marker)+2:]
+	}
+
+	hunk.OrigRanges = ranges[:len(ranges)-1]
+	hunk.OrigRange = hunk.OrigRanges[0]
+	hunk.NewRange = ranges[len(ranges)-1]
+
+	p.parentCounts = make([]int, len(hunk.OrigRanges))
+	p.parentLeft = make([]int, len(hunk.OrigRanges))
+	p.inHunk = hunk.NewRange.Length > 0
+	for i, r := range hunk.OrigRanges {
+		p.parentCounts[i], p.parentLeft[i] = r.Start, r.Length
+		p.inHunk = p.inHunk || r.Length > 0
+	}
+	p.addedCount, p.newLeft = hunk.NewRange.Start, hunk.NewRange.Length
+	p.removedCount, p.origLeft = p.parentCounts[0], p.parentLeft[0]
+	return nil
+}
+
+// parseRange parses a "<start>[,<length>]" hunk range.
+func parseRange(s string) (DiffRange, error) {
+	r := DiffRange{Length: 1}
+	start, length := s, ""
+	if i := strings.IndexByte(s, ','); i >= 0 {
+		start, length = s[:i], s[i+1:]
+	}
+	var err error
+	if r.Start, err = atoi32(start); err != nil {
+		return r, err
+	}
+	if length != "" {
+		if r.Length, err = atoi32(length); err != nil {
+			return r, err
+		}
+	}
+	return r, nil
+}
+
+// parseCombinedLine parses a line of a combined diff hunk. The line has a
+// column for each parent. A line with a "-" column is not in the merge
+// result, and is in the parents with "-" columns. Any other line is in the
+// result, and in the parents with " " columns but not those with "+".
+func (p *parser) parseCombinedLine(hunk *DiffChunk, l string) error {
+	n := len(hunk.OrigRanges)
+	if len(l) < n || strings.Trim(l[:n], " +-") != "" {
+		if p.opts.SkipBadLines {
+			p.diff.ParseWarnings = append(p.diff.ParseWarnings,
+				fmt.Sprintf("hunk %d of %q: skipped line %q", len(p.file.Chunks), p.file.name(), l))
+			return nil
+		}
+		return p.errorf(l, "could not parse line mode")
+	}
+	cols := l[:n]
+	inNew := !strings.Contains(cols, "-")
+
+	line := DiffLine{Mode: Unchanged, Position: p.diffPosCount}
+	line.Content, line.Terminator = p.lineContent(l[n:])
+	switch {
+	case !inNew:
+		line.Mode = Removed
+	case strings.Contains(cols, "+"):
+		line.Mode = Added
+	}
+	if inNew {
+		line.NewNumber = p.addedCount
+	}
+	inParent := func(i int) bool {
+		if inNew {
+			return cols[i] == ' '
+		}
+		return cols[i] == '-'
+	}
+	if inParent(0) {
+		line.OrigNumber = p.parentCounts[0]
+	}
+
+	// The line is in WholeRange once: from the result if it is there, or
+	// else from the first parent that has it.
+	inWhole := false
+	if inNew {
+		newLine := line
+		newLine.Number = p.addedCount
+		hunk.NewRange.Lines = append(hunk.NewRange.Lines, &newLine)
+		hunk.WholeRange.Lines = append(hunk.WholeRange.Lines, &newLine)
+		p.last = append(p.last, &newLine)
+		inWhole = true
+		p.addedCount++
+		p.newLeft--
+	}
+	for i := range cols {
+		if !inParent(i) {
+			continue
+		}
+		origLine := line
+		origLine.Number, origLine.OrigNumber = p.parentCounts[i], p.parentCounts[i]
+		hunk.OrigRanges[i].Lines = append(hunk.OrigRanges[i].Lines, &origLine)
+		if !inWhole {
+			hunk.WholeRange.Lines = append(hunk.WholeRange.Lines, &origLine)
+			inWhole = true
+		}
+		p.last = append(p.last, &origLine)
+		p.parentCounts[i]++
+		p.parentLeft[i]--
+	}
+	hunk.OrigRange = hunk.OrigRanges[0]
+	p.removedCount, p.origLeft = p.parentCounts[0], p.parentLeft[0]
+
+	if p.newLeft > 0 {
+		return nil
+	}
+	for _, left := range p.parentLeft {
+		if left > 0 {
+			return nil
+		}
+	}
+	p.inHunk = false
+	return p.endHunk()
+}
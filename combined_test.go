@@ -0,0 +1,93 @@
+// Copyright (c) 2015 Jesse Meek <https://github.com/waigani>
+// This program is Free Software see LICENSE file for details.
+
+package diffparser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const combinedDiff = `diff --cc file1
+index 02dc020,9550552..76146f3
+--- a/file1
++++ b/file1
+@@@ -1,3 -1,3 +1,4 @@@ heading
+  one
+- two main
+ -two side
+++two merged
+  three
+++four
+diff --git a/file2 b/file2
+index 504d2a1..50ccec3 100644
+--- a/file2
++++ b/file2
+@@ -1 +1 @@
+-a
++b
+`
+
+func TestCombinedDiff(t *testing.T) {
+	diff, err := Parse(combinedDiff)
+	require.NoError(t, err)
+	require.Len(t, diff.Files, 2)
+
+	file := diff.Files[0]
+	require.Equal(t, "file1", file.OrigName)
+	require.Equal(t, "file1", file.NewName)
+	require.Equal(t, "02dc020,9550552", file.OrigSHA)
+	require.Empty(t, file.ExtraHeaders)
+
+	chunk := file.Chunks[0]
+	require.Equal(t, "heading", chunk.ChunkHeader)
+	require.Len(t, chunk.OrigRanges, 2)
+	require.Equal(t, 1, chunk.OrigRanges[1].Start)
+	require.Equal(t, 3, chunk.OrigRanges[1].Length)
+	require.Equal(t, chunk.OrigRanges[0].Lines, chunk.OrigRange.Lines)
+	require.Equal(t, 4, chunk.NewRange.Length)
+
+	var got []string
+	for _, l := range chunk.WholeRange.Lines {
+		got = append(got, l.Mode.prefix()+l.Content)
+	}
+	require.Equal(t, []string{" one", "-two main", "-two side", "+two merged", " three", "+four"}, got)
+
+	var result []string
+	for _, l := range chunk.NewRange.Lines {
+		result = append(result, l.Content)
+	}
+	require.Equal(t, []string{"one", "two merged", "three", "four"}, result)
+	require.Equal(t, 4, chunk.NewRange.Lines[3].Number)
+
+	for _, r := range chunk.OrigRanges {
+		require.Len(t, r.Lines, 3)
+		require.Equal(t, 3, r.Lines[2].Number)
+	}
+	require.Equal(t, "two main", chunk.OrigRanges[0].Lines[1].Content)
+	require.Equal(t, "two side", chunk.OrigRanges[1].Lines[1].Content)
+
+	// The hunk ends where its ranges do.
+	require.Nil(t, diff.Files[1].Chunks[0].OrigRanges)
+	require.Len(t, diff.Files[1].Chunks[0].WholeRange.Lines, 2)
+
+	_, err = ParseWithOptions(combinedDiff, ParseOptions{Strict: true})
+	require.NoError(t, err)
+}
+
+func TestCombinedHunkHeader(t *testing.T) {
+	for _, header := range []string{
+		"@@@ -1,3 +1,4 @@@",
+		"@@@ -1,3 -1,3 -1,3 +1,4 @@@",
+		"@@@ -1,3 -1,3 @@@",
+		"@@@ -1,x -1,3 +1,4 @@@",
+		"@@@",
+	} {
+		_, err := Parse("diff --cc file1\n--- a/file1\n+++ b/file1\n" + header + "\n")
+		require.Error(t, err, header)
+	}
+
+	_, err := Parse("diff --cc f\n@@@\n")
+	require.EqualError(t, err, `line 2: could not parse hunk header: "@@@"`)
+}
@@ -0,0 +1,44 @@
+// Copyright (c) 2015 Jesse Meek <https://github.com/waigani>
+// This program is Free Software see LICENSE file for details.
+
+package diffparser
+
+// SemanticEqual reports whether d and other make the same change: the same
+// files, in the same order, with the same names and modes, and the same
+// sequence of added and removed line content. Context lines, line numbers,
+// positions and the way lines are split into hunks are ignored, so two
+// diffs of one edit generated with different amounts of context are equal.
+func (d *Diff) SemanticEqual(other *Diff) bool {
+	if len(d.Files) != len(other.Files) {
+		return false
+	}
+	for i, f := range d.Files {
+		o := other.Files[i]
+		if f.Mode != o.Mode || f.OrigName != o.OrigName || f.NewName != o.NewName {
+			return false
+		}
+		a, b := f.changedLines(), o.changedLines()
+		if len(a) != len(b) {
+			return false
+		}
+		for j := range a {
+			if a[j].Mode != b[j].Mode || a[j].Content != b[j].Content {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// changedLines returns the file's added and removed lines in diff order.
+func (f *DiffFile) changedLines() []*DiffLine {
+	var lines []*DiffLine
+	for _, h := range f.Chunks {
+		for _, l := range h.WholeRange.Lines {
+			if l.Mode != Unchanged {
+				lines = append(lines, l)
+			}
+		}
+	}
+	return lines
+}
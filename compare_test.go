@@ -0,0 +1,53 @@
+// Copyright (c) 2015 Jesse Meek <https://github.com/waigani>
+// This program is Free Software see LICENSE file for details.
+
+package diffparser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSemanticEqual(t *testing.T) {
+	wide, err := Parse(`diff --git a/file1 b/file1
+index 504d2a1..50ccec3 100644
+--- a/file1
++++ b/file1
+@@ -1,7 +1,7 @@
+ one
+ two
+ three
+-four
++cuatro
+ five
+ six
+ seven
+`)
+	require.NoError(t, err)
+
+	narrow, err := Parse(`diff --git a/file1 b/file1
+index 504d2a1..50ccec3 100644
+--- a/file1
++++ b/file1
+@@ -4 +4 @@
+-four
++cuatro
+`)
+	require.NoError(t, err)
+
+	other, err := Parse(`diff --git a/file1 b/file1
+index 504d2a1..50ccec3 100644
+--- a/file1
++++ b/file1
+@@ -4 +4 @@
+-four
++quatre
+`)
+	require.NoError(t, err)
+
+	require.True(t, wide.SemanticEqual(narrow))
+	require.True(t, narrow.SemanticEqual(wide))
+	require.False(t, wide.SemanticEqual(other))
+	require.False(t, wide.SemanticEqual(setup(t)))
+}
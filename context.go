@@ -0,0 +1,343 @@
+// Copyright (c) 2015 Jesse Meek <https://github.com/waigani>
+// This program is Free Software see LICENSE file for details.
+
+package diffparser
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// reContextOrigRange and reContextNewRange match the "*** <start>[,<end>] ****"
+// and "--- <start>[,<end>] ----" lines that start the original and new
+// sides of a context format hunk.
+var (
+	reContextOrigRange = regexp.MustCompile(`^\*\*\* (\d+)(?:,(\d+))? \*\*\*\*$`)
+	reContextNewRange  = regexp.MustCompile(`^--- (\d+)(?:,(\d+))? ----$`)
+)
+
+// contextHunkSeparator is the line that starts each hunk of a context diff.
+const contextHunkSeparator = "***************"
+
+// Sections of a context diff, in the order ParseContext reads them.
+const (
+	contextBetweenFiles = iota
+	contextFileHeader   // after the "***" file line
+	contextBetweenHunks // after the "---" file line or a hunk
+	contextOrigRange    // after the hunk separator
+	contextOrig         // in the original side of a hunk
+	contextNew          // in the new side of a hunk
+)
+
+// contextLine is a line of one side of a context format hunk.
+type contextLine struct {
+	mark      byte // ' ', '-', '+' or '!'
+	content   string
+	noNewline bool
+}
+
+// contextParser holds the state of ParseContext between lines.
+type contextParser struct {
+	diff *Diff
+	file *DiffFile
+
+	section    int
+	diffHeader string // the "diff" line of the file, if any
+	headerAt   int    // offset of the file's first line
+	pos        int    // position of the line just read in the file's diff
+
+	lineIndex int // 0-based index of the current line
+
+	heading               string
+	origStart, newStart   int
+	origLength, newLength int // as the range lines give them, or -1
+	origLines, newLines   []contextLine
+}
+
+// ParseContext parses a context format diff, such as produced by
+// "diff -c", into the same Diff structure as Parse. Each hunk's "!"
+// (changed) lines become removed lines followed by added lines, so that
+// the hunk reads as its unified form would, and its Position and String
+// are those of the unified form. Binary files and lines between files are
+// ignored.
+func ParseContext(s string) (*Diff, error) {
+	p := &contextParser{diff: &Diff{Raw: s}}
+	crlf := strings.HasSuffix(strings.SplitAfterN(s, "\n", 2)[0], "\r\n")
+	offset := 0
+	for i, raw := range strings.SplitAfter(s, "\n") {
+		if raw == "" {
+			break
+		}
+		l := strings.TrimSuffix(raw, "\n")
+		if crlf {
+			l = strings.TrimSuffix(l, "\r")
+		}
+		p.lineIndex = i
+		if err := p.parseLine(l, offset); err != nil {
+			return nil, err
+		}
+		offset += len(raw)
+	}
+
+	switch p.section {
+	case contextOrigRange, contextOrig:
+		return nil, fmt.Errorf("hunk %d of %q: diff ends before the new lines", len(p.file.Chunks)+1, p.file.name())
+	case contextNew:
+		if err := p.endHunk(); err != nil {
+			return nil, err
+		}
+	}
+	if len(p.diff.Files) == 0 {
+		p.diff.UnparsedPrefix = s
+	}
+	return p.diff, nil
+}
+
+// parseLine parses l, the next line of the diff, which starts at offset.
+func (p *contextParser) parseLine(l string, offset int) error {
+	switch p.section {
+	case contextFileHeader:
+		if !strings.HasPrefix(l, "--- ") {
+			return p.errorf(l, `expected a "---" file line`)
+		}
+		if p.diffHeader == "" {
+			p.file.DiffHeader += "\n" + l
+		}
+		if name := fileLineName(l[len("--- "):]); name == "/dev/null" {
+			p.file.Mode = Deleted
+			p.file.NewName = ""
+		} else {
+			p.file.NewName = strings.TrimPrefix(name, "b/")
+		}
+		p.diffHeader = ""
+		p.section = contextBetweenHunks
+		return nil
+	case contextOrigRange:
+		m := reContextOrigRange.FindStringSubmatch(l)
+		if m == nil {
+			return p.errorf(l, "could not parse hunk header")
+		}
+		var err error
+		if p.origStart, p.origLength, err = contextRange(m); err != nil {
+			return p.errorf(l, "could not parse hunk header")
+		}
+		p.section = contextOrig
+		return nil
+	case contextOrig:
+		if m := reContextNewRange.FindStringSubmatch(l); m != nil {
+			var err error
+			if p.newStart, p.newLength, err = contextRange(m); err != nil {
+				return p.errorf(l, "could not parse hunk header")
+			}
+			p.section = contextNew
+			return nil
+		}
+		return p.addLine(&p.origLines, l, "-!")
+	case contextNew:
+		if isContextHunkLine(l, "+!") {
+			return p.addLine(&p.newLines, l, "+!")
+		}
+		if err := p.endHunk(); err != nil {
+			return err
+		}
+		p.section = contextBetweenHunks
+	}
+
+	switch {
+	case strings.HasPrefix(l, contextHunkSeparator) && p.file != nil && p.section == contextBetweenHunks:
+		p.heading = strings.TrimSpace(l[len(contextHunkSeparator):])
+		p.section = contextOrigRange
+	case strings.HasPrefix(l, "*** "):
+		p.startFile(l, offset)
+	case strings.HasPrefix(l, "diff "):
+		p.diffHeader, p.headerAt = l, offset
+		p.file = nil
+		p.section = contextBetweenFiles
+	default:
+		// Lines such as "Only in" and "Binary files" end the file.
+		p.diffHeader = ""
+		p.file = nil
+		p.section = contextBetweenFiles
+	}
+	return nil
+}
+
+// startFile starts a new modified file at its "***" line l.
+func (p *contextParser) startFile(l string, offset int) {
+	f := &DiffFile{DiffHeader: l, Mode: Modified}
+	if p.diffHeader != "" {
+		f.DiffHeader = p.diffHeader
+		offset = p.headerAt
+	}
+	if name := fileLineName(l[len("*** "):]); name == "/dev/null" {
+		f.Mode = New
+	} else {
+		f.OrigName = strings.TrimPrefix(name, "a/")
+	}
+	if len(p.diff.Files) == 0 {
+		p.diff.UnparsedPrefix = p.diff.Raw[:offset]
+	}
+	p.diff.Files = append(p.diff.Files, f)
+	p.file = f
+	p.section = contextFileHeader
+}
+
+// addLine adds l, a line of one side of a hunk whose changed lines are
+// marked by one of marks, to lines.
+func (p *contextParser) addLine(lines *[]contextLine, l, marks string) error {
+	if l == noNewlineMarker && len(*lines) > 0 {
+		(*lines)[len(*lines)-1].noNewline = true
+		return nil
+	}
+	if !isContextHunkLine(l, marks) {
+		return p.errorf(l, "could not parse line mode")
+	}
+	*lines = append(*lines, contextLine{mark: l[0], content: l[2:]})
+	return nil
+}
+
+// isContextHunkLine reports whether l is a context line, a line marked by
+// one of marks, or a "\ No newline at end of file" marker.
+func isContextHunkLine(l, marks string) bool {
+	if l == noNewlineMarker {
+		return true
+	}
+	return len(l) >= 2 && l[1] == ' ' && (l[0] == ' ' || strings.IndexByte(marks, l[0]) >= 0)
+}
+
+// contextRange returns the start and length of the range a range line
+// match m gives, with a length of -1 for a single line number. That is
+// either one line or, for an empty side, the line before it, as the start
+// of an empty unified range is.
+func contextRange(m []string) (start, length int, err error) {
+	if start, err = atoi32(m[1]); err != nil {
+		return 0, 0, err
+	}
+	if m[2] == "" {
+		return start, -1, nil
+	}
+	end, err := atoi32(m[2])
+	if err != nil {
+		return 0, 0, err
+	}
+	return start, end - start + 1, nil
+}
+
+// endHunk adds the hunk just read to the file. A side with no changed
+// lines is left out of a context diff, and is made from the other side's
+// context lines.
+func (p *contextParser) endHunk() error {
+	orig, new := p.origLines, p.newLines
+	p.origLines, p.newLines = nil, nil
+	if len(orig) == 0 {
+		orig = contextOnly(new)
+	}
+	if len(new) == 0 {
+		new = contextOnly(orig)
+	}
+
+	file := p.file
+	for _, side := range []struct {
+		length, n int
+	}{{p.origLength, len(orig)}, {p.newLength, len(new)}} {
+		if side.length >= 0 && side.length != side.n {
+			return fmt.Errorf("hunk %d of %q: header declares %d lines, found %d",
+				len(file.Chunks)+1, file.name(), side.length, side.n)
+		}
+	}
+
+	if len(file.Chunks) == 0 {
+		p.pos = 0
+	} else {
+		p.pos++
+	}
+	hunk := &DiffChunk{
+		ChunkHeader:    p.heading,
+		OrigRange:      DiffRange{Start: p.origStart, Length: len(orig)},
+		NewRange:       DiffRange{Start: p.newStart, Length: len(new)},
+		HeaderPosition: p.pos,
+	}
+	file.Chunks = append(file.Chunks, hunk)
+
+	origNumber, newNumber := p.origStart, p.newStart
+	add := func(l contextLine, mode DiffLineMode) {
+		p.pos++
+		line := &DiffLine{Mode: mode, Content: l.content, Position: p.pos, NoNewline: l.noNewline}
+		if mode == Added {
+			line.Number, line.NewNumber = newNumber, newNumber
+			newNumber++
+			hunk.NewRange.Lines = append(hunk.NewRange.Lines, line)
+		} else {
+			line.Number, line.OrigNumber = origNumber, origNumber
+			origNumber++
+			hunk.OrigRange.Lines = append(hunk.OrigRange.Lines, line)
+		}
+		hunk.WholeRange.Lines = append(hunk.WholeRange.Lines, line)
+		if l.noNewline {
+			p.pos++
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(orig) || j < len(new) {
+		switch {
+		case i < len(orig) && orig[i].mark == '-':
+			add(orig[i], Removed)
+			i++
+		case j < len(new) && new[j].mark == '+':
+			add(new[j], Added)
+			j++
+		case i < len(orig) && orig[i].mark == '!':
+			for ; i < len(orig) && orig[i].mark == '!'; i++ {
+				add(orig[i], Removed)
+			}
+			for ; j < len(new) && new[j].mark == '!'; j++ {
+				add(new[j], Added)
+			}
+		case i < len(orig) && j < len(new) && orig[i].mark == ' ' && new[j].mark == ' ':
+			p.pos++
+			newLine := DiffLine{
+				Mode:       Unchanged,
+				Number:     newNumber,
+				OrigNumber: origNumber,
+				NewNumber:  newNumber,
+				Content:    new[j].content,
+				Position:   p.pos,
+				NoNewline:  new[j].noNewline,
+			}
+			origLine := newLine
+			origLine.Number = origNumber
+			origLine.NoNewline = orig[i].noNewline
+			hunk.NewRange.Lines = append(hunk.NewRange.Lines, &newLine)
+			hunk.OrigRange.Lines = append(hunk.OrigRange.Lines, &origLine)
+			hunk.WholeRange.Lines = append(hunk.WholeRange.Lines, &newLine)
+			if newLine.NoNewline || origLine.NoNewline {
+				p.pos++
+			}
+			origNumber++
+			newNumber++
+			i++
+			j++
+		default:
+			return fmt.Errorf("hunk %d of %q: original and new lines do not line up", len(file.Chunks), file.name())
+		}
+	}
+	return nil
+}
+
+// contextOnly returns the context lines of lines.
+func contextOnly(lines []contextLine) []contextLine {
+	var context []contextLine
+	for _, l := range lines {
+		if l.mark == ' ' {
+			context = append(context, l)
+		}
+	}
+	return context
+}
+
+// errorf returns a ParseError for line, the current line.
+func (p *contextParser) errorf(line, reason string) error {
+	return &ParseError{Line: p.lineIndex, Text: line, Reason: reason}
+}
@@ -0,0 +1,124 @@
+// Copyright (c) 2015 Jesse Meek <https://github.com/waigani>
+// This program is Free Software see LICENSE file for details.
+
+package diffparser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const contextDiff = `Only in a: other
+diff -c a/file1 b/file1
+*** a/file1	2020-01-01 00:00:00.000000000 +0000
+--- b/file1	2020-01-02 00:00:00.000000000 +0000
+***************
+*** 1,5 ****
+! one
+! two
+  three
+  four
+- five
+--- 1,5 ----
+! uno
+  three
+  four
++ cinco
++ seis
+***************
+*** 11,13 ****
+--- 11,14 ----
+  eleven
+  twelve
+  thirteen
++ fourteen
+\ No newline at end of file
+*** file2	2020-01-01 00:00:00.000000000 +0000
+--- /dev/null	1970-01-01 00:00:00.000000000 +0000
+***************
+*** 1,2 ****
+- x
+- y
+--- 0 ----
+`
+
+// contextDiffUnified is contextDiff in the unified format.
+const contextDiffUnified = `diff --git a/file1 b/file1
+--- a/file1
++++ b/file1
+@@ -1,5 +1,5 @@
+-one
+-two
++uno
+ three
+ four
+-five
++cinco
++seis
+@@ -11,3 +11,4 @@
+ eleven
+ twelve
+ thirteen
++fourteen
+\ No newline at end of file
+diff --git a/file2 b/file2
+--- a/file2
++++ /dev/null
+@@ -1,2 +0,0 @@
+-x
+-y
+`
+
+func TestParseContext(t *testing.T) {
+	diff, err := ParseContext(contextDiff)
+	require.NoError(t, err)
+	require.Equal(t, "Only in a: other\n", diff.UnparsedPrefix)
+	require.Len(t, diff.Files, 2)
+
+	file := diff.Files[0]
+	require.Equal(t, "diff -c a/file1 b/file1", file.DiffHeader)
+	require.Equal(t, Modified, file.Mode)
+	require.Equal(t, "file1", file.OrigName)
+	require.Equal(t, "file1", file.NewName)
+
+	file = diff.Files[1]
+	require.Equal(t, Deleted, file.Mode)
+	require.Equal(t, "file2", file.OrigName)
+	require.Equal(t, "", file.NewName)
+
+	// The hunks are those of the unified diff.
+	want, err := Parse(contextDiffUnified)
+	require.NoError(t, err)
+	for i, f := range want.Files {
+		for _, h := range f.Chunks {
+			h.RawHeader = ""
+		}
+		require.Equal(t, f.Chunks, diff.Files[i].Chunks, "file %d", i)
+	}
+	require.Equal(t, "@@ -1,5 +1,5 @@\n", diff.Files[0].Chunks[0].String()[:16])
+}
+
+func TestParseContextErrors(t *testing.T) {
+	for _, test := range []struct {
+		diff, err string
+	}{{
+		diff: "*** a\n+++ b\n",
+		err:  `line 2: expected a "---" file line: "+++ b"`,
+	}, {
+		diff: "*** a\n--- b\n***************\n@@ -1 +1 @@\n",
+		err:  `line 4: could not parse hunk header: "@@ -1 +1 @@"`,
+	}, {
+		diff: "*** a\n--- b\n***************\n*** 1 ****\n+ one\n--- 1 ----\n",
+		err:  `line 5: could not parse line mode: "+ one"`,
+	}, {
+		diff: "*** a\n--- b\n***************\n*** 1,2 ****\n- one\n--- 1 ----\n  two\n",
+		err:  `hunk 1 of "b": header declares 2 lines, found 1`,
+	}, {
+		diff: "*** a\n--- b\n***************\n*** 1 ****\n- one\n",
+		err:  `hunk 1 of "b": diff ends before the new lines`,
+	}} {
+		_, err := ParseContext(test.diff)
+		require.EqualError(t, err, test.err)
+	}
+}
@@ -4,11 +4,15 @@
 package diffparser
 
 import (
+	"bufio"
+	"io"
+	"math"
+	"os"
 	"regexp"
 	"strconv"
 	"strings"
 
-	"errors"
+	"fmt"
 )
 
 // FileMode represents the file status in a diff
@@ -21,8 +25,28 @@ const (
 	Modified
 	// New if the file is created and there is no diff
 	New
+	// Renamed if the file is renamed, with or without changes
+	Renamed
+	// Copied if the file is copied from another, with or without changes
+	Copied
 )
 
+var fileModeNames = map[FileMode]string{
+	Deleted:  "Deleted",
+	Modified: "Modified",
+	New:      "New",
+	Renamed:  "Renamed",
+	Copied:   "Copied",
+}
+
+// String returns the name of the mode, such as "Modified".
+func (m FileMode) String() string {
+	if name, ok := fileModeNames[m]; ok {
+		return name
+	}
+	return "FileMode(" + strconv.Itoa(int(m)) + ")"
+}
+
 // DiffRange contains the DiffLine's
 type DiffRange struct {
 
@@ -36,6 +60,13 @@ type DiffRange struct {
 	Lines []*DiffLine
 }
 
+// End returns the number of the last line of the range, Start+Length-1. An
+// empty range, as on the original side of a pure insertion, has no last
+// line and End returns Start-1, so End() < Start.
+func (r DiffRange) End() int {
+	return r.Start + r.Length - 1
+}
+
 // DiffLineMode tells the line if added, removed or unchanged
 type DiffLineMode rune
 
@@ -48,20 +79,77 @@ const (
 	Unchanged
 )
 
+var lineModeNames = map[DiffLineMode]string{
+	Added:     "Added",
+	Removed:   "Removed",
+	Unchanged: "Unchanged",
+}
+
+// String returns the name of the mode, such as "Added".
+func (m DiffLineMode) String() string {
+	if name, ok := lineModeNames[m]; ok {
+		return name
+	}
+	return "DiffLineMode(" + strconv.Itoa(int(m)) + ")"
+}
+
 // DiffLine is the least part of an actual diff
 type DiffLine struct {
-	Mode     DiffLineMode
-	Number   int
-	Content  string
-	Position int // the line in the diff
+	Mode   DiffLineMode
+	Number int // the line in the file on this line's side
+	// OrigNumber and NewNumber are the line's numbers in the original and
+	// new file, or zero if the line is not on that side.
+	OrigNumber int
+	NewNumber  int
+	Content    string
+	// Position is the line's position in the file's diff, counted from
+	// the file's first "@@" header at 0. Later "@@" headers take up a
+	// position each, as in GitHub's diff positions.
+	Position int
+	// NoNewline is set on the last line of a file that has no terminating
+	// newline, marked in the diff by "\ No newline at end of file".
+	NoNewline bool
+	// Terminator is the line's terminator in the file, "\n" or "\r\n",
+	// or "" for a last line without one. It is only set by a parse with
+	// the RecordTerminators option, which leaves the "\r" of a CRLF line
+	// out of Content.
+	Terminator string
+}
+
+// text returns the content of the line as it is in the file, without its
+// final "\n".
+func (l *DiffLine) text() string {
+	if l.Terminator == "\r\n" {
+		return l.Content + "\r"
+	}
+	return l.Content
+}
+
+// Trimmed returns the line's content without leading and trailing white
+// space.
+func (l *DiffLine) Trimmed() string {
+	return strings.TrimSpace(l.Content)
 }
 
 // DiffChunk is a group of difflines
 type DiffChunk struct {
 	ChunkHeader string
+	RawHeader   string // the "@@" line as it appeared in the diff
 	OrigRange   DiffRange
-	NewRange    DiffRange
-	WholeRange  DiffRange
+	// OrigRanges holds the range of each parent of a combined diff
+	// hunk, as git prints for merges, and is nil otherwise. OrigRange is
+	// the range of the first parent. The hunk's lines are Added if they
+	// are not in some parent and Removed if they are not in the result.
+	// Combined hunks can be read but not written back or applied.
+	OrigRanges []DiffRange
+	NewRange   DiffRange
+	// WholeRange holds each line of the hunk body once, in diff order.
+	// Its added and context lines are those of NewRange and its removed
+	// lines those of OrigRange. Context lines have both an OrigNumber and
+	// a NewNumber, so WholeRange alone gives both gutters of a unified
+	// view.
+	WholeRange     DiffRange
+	HeaderPosition int // the line of the @@ header in the diff
 }
 
 // DiffFile is the sum of diffhunks and holds the changes of the file features
@@ -71,6 +159,134 @@ type DiffFile struct {
 	OrigName   string
 	NewName    string
 	Chunks     []*DiffChunk
+
+	// IsBinary is set for binary files, whose changes are not parsed into
+	// Chunks.
+	IsBinary bool
+
+	// BinaryPatch holds the lines that follow the "GIT binary patch" line
+	// of a binary file: the "literal" or "delta" blocks that change the
+	// file and then undo it, each ended by a blank line. String writes
+	// them back as they are.
+	BinaryPatch []string
+
+	// SummaryAdditions and SummaryDeletions are the line counts of a file
+	// read from a summary, such as by ParseNumstat, that has no hunks to
+	// count. Additions and Deletions include them.
+	SummaryAdditions, SummaryDeletions int
+
+	// Blob hashes and mode from the "index" line. The mode is empty if the
+	// line has none, and a hash is all zeros for the missing side of a new
+	// or deleted file.
+	OrigSHA   string
+	NewSHA    string
+	IndexMode string
+
+	// File modes from the "old mode" and "new mode" lines of a mode change.
+	OldMode string
+	NewMode string
+
+	// ExtraHeaders holds the header lines of the file that Parse does not
+	// model, and its "new file mode", "deleted file mode" and "similarity
+	// index" lines, in diff order.
+	ExtraHeaders []string
+
+	// Similarity is the percentage of a renamed or copied file that is
+	// unchanged, as git estimates it, or zero if not given.
+	Similarity int
+
+	// IsSymlink is set when the file is a symlink on either side of the
+	// change, going by its 120000 mode. The content of a symlink is its
+	// target path, not source.
+	IsSymlink bool
+
+	// IsSubmodule is set when the file is a submodule: its mode is 160000,
+	// or its hunks change only a "Subproject commit" line. The commits
+	// before and after the change are set from those lines.
+	IsSubmodule         bool
+	OrigSubmoduleCommit string
+	NewSubmoduleCommit  string
+}
+
+// reIndex matches an "index <orig>..<new> [<mode>]" header line. Hashes may
+// be abbreviated or full length, combined diffs list several comma separated
+// original hashes, the hashes may be separated by "..." as some tools write
+// them, and the mode is optional.
+var reIndex = regexp.MustCompile(`^index\s+([0-9a-f,]+)\.{2,3}([0-9a-f]+)(?:\s+([0-7]+))?\s*$`)
+
+// reBinaryFiles matches the line git prints in place of hunks when a
+// binary file changed and no binary patch was requested.
+var reBinaryFiles = regexp.MustCompile(`^Binary files (.+) and (.+) differ$`)
+
+var reSubproject = regexp.MustCompile(`^Subproject commit ([0-9a-f]+)$`)
+
+// Git file modes of symlinks and submodules.
+const (
+	symlinkMode   = "120000"
+	submoduleMode = "160000"
+)
+
+// IsNewBlob reports whether the "index" line gives an all-zero original
+// hash, which git writes when the file had no blob before the change.
+func (f *DiffFile) IsNewBlob() bool {
+	return isZeroHash(f.OrigSHA)
+}
+
+// IsDeletedBlob reports whether the "index" line gives an all-zero new
+// hash, which git writes when the file has no blob after the change.
+func (f *DiffFile) IsDeletedBlob() bool {
+	return isZeroHash(f.NewSHA)
+}
+
+// isZeroHash reports whether sha, which may list a combined diff's hashes
+// separated by commas, is made of zeros only.
+func isZeroHash(sha string) bool {
+	return sha != "" && strings.Trim(sha, "0,") == ""
+}
+
+// hasMode reports whether a header line gives the file mode on either side
+// of the change.
+func (f *DiffFile) hasMode(mode string) bool {
+	for _, m := range []string{f.IndexMode, f.OldMode, f.NewMode} {
+		if m == mode {
+			return true
+		}
+	}
+	for _, h := range f.ExtraHeaders {
+		if h == "new file mode "+mode || h == "deleted file mode "+mode {
+			return true
+		}
+	}
+	return false
+}
+
+// detectSubmodule marks the file as a submodule when a header gives it the
+// submodule mode, and sets its submodule commits when its hunks hold nothing
+// but a "Subproject commit" line on either side.
+func (f *DiffFile) detectSubmodule() {
+	f.IsSubmodule = f.hasMode(submoduleMode)
+
+	var orig, new string
+	for _, h := range f.Chunks {
+		for _, l := range h.WholeRange.Lines {
+			m := reSubproject.FindStringSubmatch(l.Content)
+			if m == nil {
+				return
+			}
+			switch {
+			case l.Mode == Removed && orig == "":
+				orig = m[1]
+			case l.Mode == Added && new == "":
+				new = m[1]
+			default:
+				return
+			}
+		}
+	}
+	f.OrigSubmoduleCommit, f.NewSubmoduleCommit = orig, new
+	if orig != "" || new != "" {
+		f.IsSubmodule = true
+	}
 }
 
 // Diff is the collection of DiffFiles
@@ -78,7 +294,72 @@ type Diff struct {
 	Files []*DiffFile
 	Raw   string `sql:"type:text"`
 
+	// UnparsedPrefix holds any text before the first file header, such as
+	// a tool's preamble, which Parse skips.
+	UnparsedPrefix string `sql:"type:text"`
+
 	PullID uint `sql:"index"`
+
+	// ParseWarnings describes the lines skipped by a parse with the
+	// SkipBadLines option.
+	ParseWarnings []string `sql:"-"`
+}
+
+// FilesFor returns every file in the diff whose original or new name is
+// path, in diff order. Concatenated diffs may touch the same path in more
+// than one DiffFile.
+func (d *Diff) FilesFor(path string) []*DiffFile {
+	var files []*DiffFile
+	for _, f := range d.Files {
+		if f.OrigName == path || f.NewName == path {
+			files = append(files, f)
+		}
+	}
+	return files
+}
+
+// Walk calls fn for each line of the diff, in file, hunk and then
+// WholeRange order, with the line's file and hunk.
+func (d *Diff) Walk(fn func(*DiffFile, *DiffChunk, *DiffLine)) {
+	for _, f := range d.Files {
+		for _, h := range f.Chunks {
+			for _, l := range h.WholeRange.Lines {
+				fn(f, h, l)
+			}
+		}
+	}
+}
+
+// GetFile returns the first file in the diff whose new or original name is
+// name, or nil if there is none. A rename matches both its names, so
+// GetFileByNewName or GetFileByOrigName is clearer when they may differ.
+func (d *Diff) GetFile(name string) *DiffFile {
+	if files := d.FilesFor(name); len(files) > 0 {
+		return files[0]
+	}
+	return nil
+}
+
+// GetFileByNewName returns the first file in the diff whose new name is
+// name, or nil if there is none.
+func (d *Diff) GetFileByNewName(name string) *DiffFile {
+	for _, f := range d.Files {
+		if f.NewName == name {
+			return f
+		}
+	}
+	return nil
+}
+
+// GetFileByOrigName returns the first file in the diff whose original name
+// is name, or nil if there is none.
+func (d *Diff) GetFileByOrigName(name string) *DiffFile {
+	for _, f := range d.Files {
+		if f.OrigName == name {
+			return f
+		}
+	}
+	return nil
 }
 
 func (d *Diff) addFile(file *DiffFile) {
@@ -86,7 +367,8 @@ func (d *Diff) addFile(file *DiffFile) {
 }
 
 // Changed returns a map of filename to lines changed in that file. Deleted
-// files are ignored.
+// files are ignored. A file that appears in more than one DiffFile
+// accumulates the lines of all of them, in diff order.
 func (d *Diff) Changed() map[string][]int {
 	dFiles := make(map[string][]int)
 
@@ -97,7 +379,7 @@ func (d *Diff) Changed() map[string][]int {
 
 		for _, h := range f.Chunks {
 			for _, dl := range h.NewRange.Lines {
-				if dl.Mode == Added { // TODO(waigani) return removed
+				if dl.Mode == Added {
 					dFiles[f.NewName] = append(dFiles[f.NewName], dl.Number)
 				}
 			}
@@ -107,188 +389,1062 @@ func (d *Diff) Changed() map[string][]int {
 	return dFiles
 }
 
-func lineMode(line string) (*DiffLineMode, error) {
-	var m DiffLineMode
+// ChangedLines holds the lines changed in a file: the new line numbers of
+// its added lines and the original line numbers of its removed lines.
+type ChangedLines struct {
+	Added   []int
+	Removed []int
+}
+
+// ChangedDetailed returns a map of filename to the lines added and removed
+// in that file. Unlike Changed, deleted files are included, under their
+// original name. A file that appears in more than one DiffFile accumulates
+// the lines of all of them, in diff order.
+func (d *Diff) ChangedDetailed() map[string]*ChangedLines {
+	dFiles := make(map[string]*ChangedLines)
+
+	for _, f := range d.Files {
+		name := f.name()
+		changed := dFiles[name]
+		if changed == nil {
+			changed = &ChangedLines{}
+			dFiles[name] = changed
+		}
+
+		for _, h := range f.Chunks {
+			for _, dl := range h.NewRange.Lines {
+				if dl.Mode == Added {
+					changed.Added = append(changed.Added, dl.Number)
+				}
+			}
+			for _, dl := range h.OrigRange.Lines {
+				if dl.Mode == Removed {
+					changed.Removed = append(changed.Removed, dl.Number)
+				}
+			}
+		}
+	}
+
+	return dFiles
+}
+
+// NewFilesContent returns a map of filename to the full content of each new
+// file, rebuilt from its added lines. Every line is terminated with a
+// newline, but for a last line marked "\ No newline at end of file".
+func (d *Diff) NewFilesContent() map[string]string {
+	contents := make(map[string]string)
+
+	for _, f := range d.Files {
+		if f.Mode != New {
+			continue
+		}
+
+		var b strings.Builder
+		for _, h := range f.Chunks {
+			for _, dl := range h.NewRange.Lines {
+				b.WriteString(dl.text())
+				if !dl.NoNewline {
+					b.WriteString("\n")
+				}
+			}
+		}
+		contents[f.NewName] = b.String()
+	}
+
+	return contents
+}
+
+// UnifiedLines returns every line of the file's hunks in the order they
+// appear in the diff, with context, removed and added lines interleaved.
+func (f *DiffFile) UnifiedLines() []*DiffLine {
+	var lines []*DiffLine
+	for _, h := range f.Chunks {
+		lines = append(lines, h.WholeRange.Lines...)
+	}
+	return lines
+}
+
+// AddedRanges returns the new-side line numbers of the file's added lines
+// as inclusive [start, end] ranges, merging consecutive numbers.
+func (f *DiffFile) AddedRanges() [][2]int {
+	var ranges [][2]int
+	for _, h := range f.Chunks {
+		for _, dl := range h.NewRange.Lines {
+			if dl.Mode != Added {
+				continue
+			}
+			if n := len(ranges); n > 0 && ranges[n-1][1]+1 == dl.Number {
+				ranges[n-1][1] = dl.Number
+				continue
+			}
+			ranges = append(ranges, [2]int{dl.Number, dl.Number})
+		}
+	}
+	return ranges
+}
+
+// CoverageOverlap partitions the new-side line numbers of the file's added
+// lines into those found in covered and those that are not.
+func (f *DiffFile) CoverageOverlap(covered []int) (coveredAdded, uncoveredAdded []int) {
+	set := make(map[int]bool, len(covered))
+	for _, n := range covered {
+		set[n] = true
+	}
+
+	for _, h := range f.Chunks {
+		for _, dl := range h.NewRange.Lines {
+			if dl.Mode != Added {
+				continue
+			}
+			if set[dl.Number] {
+				coveredAdded = append(coveredAdded, dl.Number)
+			} else {
+				uncoveredAdded = append(uncoveredAdded, dl.Number)
+			}
+		}
+	}
+	return coveredAdded, uncoveredAdded
+}
+
+// AddedLinesMatching returns the added lines of the file whose content
+// matches re.
+func (f *DiffFile) AddedLinesMatching(re *regexp.Regexp) []*DiffLine {
+	var lines []*DiffLine
+	for _, h := range f.Chunks {
+		for _, dl := range h.NewRange.Lines {
+			if dl.Mode == Added && re.MatchString(dl.Content) {
+				lines = append(lines, dl)
+			}
+		}
+	}
+	return lines
+}
+
+// AddedLines returns the added lines of the file, in file order.
+func (f *DiffFile) AddedLines() []*DiffLine {
+	return f.linesOfMode(Added)
+}
+
+// RemovedLines returns the removed lines of the file, in file order.
+func (f *DiffFile) RemovedLines() []*DiffLine {
+	return f.linesOfMode(Removed)
+}
+
+// UnchangedLines returns the context lines of the file, in file order.
+// Their Number is the new-side line number.
+func (f *DiffFile) UnchangedLines() []*DiffLine {
+	return f.linesOfMode(Unchanged)
+}
+
+func (f *DiffFile) linesOfMode(mode DiffLineMode) []*DiffLine {
+	var lines []*DiffLine
+	for _, h := range f.Chunks {
+		for _, dl := range h.WholeRange.Lines {
+			if dl.Mode == mode {
+				lines = append(lines, dl)
+			}
+		}
+	}
+	return lines
+}
+
+// lineMode returns the mode of a hunk line from its first character, and
+// false if the character gives no mode.
+func lineMode(line string) (DiffLineMode, bool) {
 	switch line[:1] {
 	case " ":
-		m = Unchanged
+		return Unchanged, true
 	case "+":
-		m = Added
+		return Added, true
 	case "-":
-		m = Removed
-	default:
-		return nil, errors.New("could not parse line mode for line: \"" + line + "\"")
+		return Removed, true
 	}
-	return &m, nil
+	return 0, false
 }
 
 // Parse takes a diff, such as produced by "git diff", and parses it into a
-// Diff struct.
+// Diff struct. Plain unified diffs without "diff --git" lines, such as
+// produced by "diff -u", and Subversion diffs, whose files start at
+// "Index:" lines, are parsed too. Context format files, such as produced
+// by "diff -c", are parsed by ParseContext.
+//
+// If the first line of the diff is terminated by "\r\n" the diff itself is
+// taken to use CRLF line endings and one "\r" is stripped from each line.
+// Otherwise a trailing "\r" is part of the content of the diffed file and
+// is kept.
 func Parse(diffString string) (*Diff, error) {
-	var diff Diff
+	return ParseWithOptions(diffString, ParseOptions{})
+}
+
+// ParseOptions changes how a diff is parsed.
+type ParseOptions struct {
+	// SrcPrefix and DstPrefix are stripped from the original and new file
+	// names, as set by "git diff --src-prefix" and "--dst-prefix". They
+	// default to "a/" and "b/". Names without the prefix, as from
+	// "git diff --no-prefix", are used whole.
+	SrcPrefix string
+	DstPrefix string
+
+	// Strict makes it an error for a hunk to hold more or fewer lines than
+	// its "@@" header declares, including a " ", "+" or "-" line after the
+	// declared lines. Otherwise a hunk ends early at the next hunk or file,
+	// and lines beyond its declared length are ignored.
+	Strict bool
+
+	// SkipBadLines makes lines in a hunk that are not added, removed or
+	// context lines, such as those mangled by a mail client, be skipped
+	// with a warning in the diff's ParseWarnings. Otherwise they are an
+	// error. A line starting with a tab, as some tools write context
+	// lines, is taken as a context line instead: the tab is its mode
+	// marker, and is not part of its Content. Git's rename, copy and mode
+	// header lines before any file start one, as if a "diff --git" line
+	// came first, so that a fragment of a header still gives a file.
+	SkipBadLines bool
+
+	// RecordTerminators makes each line's terminator be kept in its
+	// Terminator field, and the "\r" of CRLF lines be left out of its
+	// Content. Otherwise a file's "\r" is part of the content.
+	RecordTerminators bool
+}
+
+// ParseError is the error Parse returns for a line it cannot parse, such as
+// a malformed hunk header or a hunk line with no mode.
+type ParseError struct {
+	Line   int    // 0-based index of the line in the diff
+	Text   string // the line, without its terminator
+	Reason string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("line %d: %s: %q", e.Line+1, e.Reason, e.Text)
+}
+
+// ParseWithOptions parses a diff as Parse does, with the given options.
+func ParseWithOptions(diffString string, opts ParseOptions) (*Diff, error) {
+	diff, err := parseReader(strings.NewReader(diffString), opts)
+	if err != nil {
+		return nil, err
+	}
 	diff.Raw = diffString
-	lines := strings.Split(diffString, "\n")
-
-	var file *DiffFile
-	var hunk *DiffChunk
-	var AddedCount int
-	var RemovedCount int
-	var inHunk bool
-	oldFilePrefix := "--- a/"
-	newFilePrefix := "+++ b/"
-
-	var diffPosCount int
-	var firstHunkInFile bool
-	// Parse each line of diff.
-	for idx, l := range lines {
-		diffPosCount++
-		switch {
-		case strings.HasPrefix(l, "diff "):
-			inHunk = false
-
-			// Start a new file.
-			file = &DiffFile{}
-			header := l
-			if len(lines) > idx+3 {
-				rein := regexp.MustCompile(`^index .+$`)
-				remp := regexp.MustCompile(`^(-|\+){3} .+$`)
-				index := lines[idx+1]
-				if rein.MatchString(index) {
-					header = header + "\n" + index
-				}
-				mp1 := lines[idx+2]
-				mp2 := lines[idx+3]
-				if remp.MatchString(mp1) && remp.MatchString(mp2) {
-					header = header + "\n" + mp1 + "\n" + mp2
-				}
-			}
-			file.DiffHeader = header
-			diff.Files = append(diff.Files, file)
-			firstHunkInFile = true
+	return diff, nil
+}
 
-			// File mode.
-			file.Mode = Modified
-		case l == "+++ /dev/null":
-			file.Mode = Deleted
-		case l == "--- /dev/null":
-			file.Mode = New
-		case strings.HasPrefix(l, oldFilePrefix):
-			file.OrigName = strings.TrimPrefix(l, oldFilePrefix)
-		case strings.HasPrefix(l, newFilePrefix):
-			file.NewName = strings.TrimPrefix(l, newFilePrefix)
-		case strings.HasPrefix(l, "@@ "):
-			if firstHunkInFile {
-				diffPosCount = 0
-				firstHunkInFile = false
-			}
+// ParseReader parses a diff read from r, as Parse does. The input is read
+// a line at a time, without limit on the line length, and is not kept: the
+// Raw field of the returned Diff is empty.
+func ParseReader(r io.Reader) (*Diff, error) {
+	return parseReader(r, ParseOptions{})
+}
+
+// ParseFile parses the diff in the named file, as ParseReader does. Errors
+// name the file.
+func ParseFile(path string) (*Diff, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
 
-			inHunk = true
-			// Start new hunk.
-			hunk = &DiffChunk{}
-			file.Chunks = append(file.Chunks, hunk)
+	diff, err := ParseReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return diff, nil
+}
 
-			// Parse hunk heading for ranges
-			re := regexp.MustCompile(`@@ \-(\d+),?(\d+)? \+(\d+),?(\d+)? @@ ?(.+)?`)
-			m := re.FindStringSubmatch(l)
-			if len(m) < 5 {
-				return nil, errors.New("Error parsing line: " + l)
-			}
-			a, err := strconv.Atoi(m[1])
-			if err != nil {
+func parseReader(r io.Reader, opts ParseOptions) (*Diff, error) {
+	br := bufio.NewReader(r)
+	p := newParser(opts)
+	for {
+		l, err := br.ReadString('\n')
+		if len(l) > 0 {
+			if err := p.readLine(l); err != nil {
 				return nil, err
 			}
-			b := a
-			if len(m[2]) > 0 {
-				b, err = strconv.Atoi(m[2])
-				if err != nil {
-					return nil, err
-				}
+		}
+		if err == io.EOF {
+			return p.finish()
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// newParser returns a parser with the given options, whose prefixes
+// default to "a/" and "b/".
+func newParser(opts ParseOptions) *parser {
+	if opts.SrcPrefix == "" {
+		opts.SrcPrefix = "a/"
+	}
+	if opts.DstPrefix == "" {
+		opts.DstPrefix = "b/"
+	}
+	return &parser{diff: &Diff{}, opts: opts}
+}
+
+// readLine parses raw, the next line of the diff including its terminator,
+// and moves on to the line after it. The first line decides whether the
+// diff uses CRLF line endings.
+func (p *parser) readLine(raw string) error {
+	if p.lineIndex == 0 {
+		p.crlf = strings.HasSuffix(raw, "\r\n")
+	}
+	if err := p.parseLine(raw); err != nil {
+		return err
+	}
+	p.lineIndex++
+	return nil
+}
+
+// parser holds the state of Parse between lines.
+type parser struct {
+	opts ParseOptions
+	diff *Diff
+	file *DiffFile
+	hunk *DiffChunk
+
+	lineIndex int // 0-based index of the current line
+
+	crlf    bool            // the diff uses CRLF line endings
+	plain   bool            // the file has no "diff " line
+	index   bool            // the file starts at a Subversion "Index:" line
+	pending bool            // the plain file has no hunk yet
+	prefix  strings.Builder // the text before the first file
+
+	lineStart int // offset in prefix of the current line
+	fileStart int // offset in prefix of the current file's first line
+
+	headerOffset int    // lines read since the file's "diff " line
+	headerLine2  string // the second line after it
+
+	addedCount      int
+	removedCount    int
+	inHunk          bool
+	last            []*DiffLine // the copies of the line just read
+	parentCounts    []int       // line counts of a combined hunk's parents
+	parentLeft      []int
+	origLeft        int // lines of the hunk still to be read
+	newLeft         int
+	inContextDiff   bool
+	context         strings.Builder // the context format diff read so far
+	contextStart    int             // index of its first line
+	contextAt       int             // offset in prefix of its first line
+	fileLine        string          // the "diff " line of the file, as read
+	fileLineIndex   int             // index of that line
+	inBinaryPatch   bool
+	binaryBlocks    int // blocks of the binary patch read
+	diffPosCount    int
+	firstHunkInFile bool
+}
+
+// reFileLine matches a "---" or "+++" file line.
+var reFileLine = regexp.MustCompile(`^(-|\+){3} .+$`)
+
+// reHunk matches a "@@ -<start>[,<length>] +<start>[,<length>] @@" hunk
+// header line and its optional section heading.
+var reHunk = regexp.MustCompile(`@@ \-(\d+),?(\d+)? \+(\d+),?(\d+)? @@ ?(.+)?`)
+
+// parseLine parses the next line of the diff, raw, including its
+// terminator.
+func (p *parser) parseLine(raw string) error {
+	if len(p.diff.Files) == 0 {
+		// Keep the text before the first file until the file starts.
+		p.lineStart = p.prefix.Len()
+		p.prefix.WriteString(raw)
+	}
+	if p.inContextDiff && !strings.HasPrefix(raw, "diff ") {
+		// A context format diff runs to the next "diff " line, and is
+		// parsed by ParseContext then.
+		p.context.WriteString(raw)
+		return nil
+	}
+	// With SkipBadLines, git's extended header lines start a file even
+	// without a "diff " line, as in a fragment of a header.
+	bare := p.file == nil && p.opts.SkipBadLines && isExtendedHeaderLine(strings.TrimRight(raw, "\r\n"))
+	if p.file == nil && !bare && !strings.HasPrefix(raw, "diff ") && !strings.HasPrefix(raw, "Index: ") &&
+		!strings.HasPrefix(raw, "--- ") {
+		// A hunk needs a file. Other lines before the first file, even
+		// ones that look like hunk lines as in a commit message's list,
+		// are skipped, but for the start of a context format diff.
+		if strings.HasPrefix(raw, "@@") {
+			return p.errorf(strings.TrimRight(raw, "\r\n"), "hunk before any file header")
+		}
+		if strings.HasPrefix(raw, "*** ") {
+			p.startContext(raw)
+		}
+		return nil
+	}
+
+	l := strings.TrimSuffix(raw, "\n")
+	if p.crlf {
+		l = strings.TrimSuffix(l, "\r")
+	}
+	if bare {
+		p.startFile(l)
+		p.addFile()
+		p.headerOffset = 3
+	}
+	file, hunk := p.file, p.hunk
+
+	// Add the index and file lines that directly follow "diff " to the
+	// file's DiffHeader.
+	if file != nil && p.headerOffset < 3 {
+		p.headerOffset++
+		switch p.headerOffset {
+		case 1:
+			if reIndex.MatchString(l) {
+				file.DiffHeader += "\n" + l
 			}
-			c, err := strconv.Atoi(m[3])
-			if err != nil {
-				return nil, err
+		case 2:
+			p.headerLine2 = l
+		case 3:
+			if reFileLine.MatchString(p.headerLine2) && reFileLine.MatchString(l) {
+				file.DiffHeader += "\n" + p.headerLine2 + "\n" + l
 			}
-			d := c
-			if len(m[4]) > 0 {
-				d, err = strconv.Atoi(m[4])
-				if err != nil {
-					return nil, err
-				}
+		}
+	}
+
+	p.diffPosCount++
+	last := p.last
+	p.last = nil
+	switch {
+	case last != nil && strings.HasPrefix(l, `\`):
+		// The marker follows the line it applies to, which may end the
+		// hunk. It is not translated in all versions of diff, so any
+		// text is accepted.
+		for _, dl := range last {
+			dl.NoNewline = true
+			// With no newline, a "\r" is not part of a terminator.
+			dl.Content = dl.text()
+			dl.Terminator = ""
+		}
+	case p.inBinaryPatch && !strings.HasPrefix(l, "diff "):
+		// Keep the payload's two blocks, and skip what follows them,
+		// such as a mail signature.
+		if p.binaryBlocks < 2 {
+			file.BinaryPatch = append(file.BinaryPatch, l)
+			if l == "" {
+				p.binaryBlocks++
 			}
-			if len(m[5]) > 0 {
-				hunk.ChunkHeader = m[5]
+		}
+	case isContextDiffLine(l, p.inHunk):
+		if err := p.endHunk(); err != nil {
+			return err
+		}
+		p.inHunk = false
+		p.startContext(raw)
+		if file != nil && p.firstHunkInFile && !p.plain && !p.index && file.OrigName == "" && file.NewName == "" {
+			// The file is no more than the "diff " line of the context
+			// format file, such as "diff -c a b", which heads it if it
+			// comes just before.
+			p.diff.Files = p.diff.Files[:len(p.diff.Files)-1]
+			p.contextAt = p.fileStart
+			if p.headerOffset == 1 {
+				p.context.Reset()
+				p.context.WriteString(p.fileLine + raw)
+				p.contextStart = p.fileLineIndex
 			}
+		}
+	case strings.HasPrefix(l, "diff ") && p.index && p.firstHunkInFile:
+		// "svn diff --git" follows the "Index:" line with a git header
+		// for the same file.
+		file.DiffHeader = l
+		p.headerOffset = 0
+		p.index = false
+		file.OrigName, file.NewName = gitHeaderNames(l, p.opts.SrcPrefix, p.opts.DstPrefix)
+	case strings.HasPrefix(l, "diff "):
+		if err := p.endHunk(); err != nil {
+			return err
+		}
+		if err := p.endContext(); err != nil {
+			return err
+		}
+		p.inHunk = false
+		p.inBinaryPatch = false
 
-			// hunk orig range.
-			hunk.OrigRange = DiffRange{
-				Start:  a,
-				Length: b,
-			}
+		file = p.startFile(l)
+		p.addFile()
+		p.fileLine, p.fileLineIndex = raw, p.lineIndex
+		p.headerOffset = 0
+		p.plain = false
+
+		// Names, unless given by later lines.
+		file.OrigName, file.NewName = gitHeaderNames(l, p.opts.SrcPrefix, p.opts.DstPrefix)
+	case strings.HasPrefix(l, "Index: ") && !p.inHunk:
+		// A Subversion diff starts each file at an "Index: <path>" line
+		// and a line of "=", followed by a unified diff. The path names
+		// the file unless the "---" and "+++" lines give other names,
+		// which lose their prefixes as in a git diff.
+		if err := p.endHunk(); err != nil {
+			return err
+		}
+		p.inBinaryPatch = false
+
+		file = p.startFile(l)
+		p.addFile()
+		p.headerOffset = 3
+		p.plain = false
+		p.index = true
+
+		name := strings.TrimPrefix(l, "Index: ")
+		file.OrigName, file.NewName = name, name
+	case p.index && p.firstHunkInFile && l != "" && strings.Trim(l, "=") == "":
+	case p.inHunk && !strings.HasPrefix(l, "@@"):
+		if !isSourceLine(l) {
+			break
+		}
+		if hunk.OrigRanges != nil {
+			return p.parseCombinedLine(hunk, l)
+		}
+		m, ok := lineMode(l)
+		if !ok && p.opts.SkipBadLines && l[0] == '\t' {
+			m, ok = Unchanged, true
+		}
+		if !ok && p.opts.SkipBadLines {
+			p.diff.ParseWarnings = append(p.diff.ParseWarnings,
+				fmt.Sprintf("hunk %d of %q: skipped line %q", len(file.Chunks), file.name(), l))
+			break
+		}
+		if !ok {
+			return p.errorf(l, "could not parse line mode")
+		}
+		line := DiffLine{
+			Mode:     m,
+			Position: p.diffPosCount,
+		}
+		line.Content, line.Terminator = p.lineContent(l[1:])
+		newLine := line
+		origLine := line
 
-			// hunk new range.
-			hunk.NewRange = DiffRange{
-				Start:  c,
-				Length: d,
+		// add lines to ranges
+		switch m {
+		case Added:
+			newLine.Number = p.addedCount
+			newLine.NewNumber = p.addedCount
+			hunk.NewRange.Lines = append(hunk.NewRange.Lines, &newLine)
+			hunk.WholeRange.Lines = append(hunk.WholeRange.Lines, &newLine)
+			p.last = []*DiffLine{&newLine}
+			p.addedCount++
+			p.newLeft--
+
+		case Removed:
+			origLine.Number = p.removedCount
+			origLine.OrigNumber = p.removedCount
+			hunk.OrigRange.Lines = append(hunk.OrigRange.Lines, &origLine)
+			hunk.WholeRange.Lines = append(hunk.WholeRange.Lines, &origLine)
+			p.last = []*DiffLine{&origLine}
+			p.removedCount++
+			p.origLeft--
+
+		case Unchanged:
+			newLine.OrigNumber, newLine.NewNumber = p.removedCount, p.addedCount
+			origLine.OrigNumber, origLine.NewNumber = p.removedCount, p.addedCount
+			newLine.Number = p.addedCount
+			hunk.NewRange.Lines = append(hunk.NewRange.Lines, &newLine)
+			hunk.WholeRange.Lines = append(hunk.WholeRange.Lines, &newLine)
+			origLine.Number = p.removedCount
+			hunk.OrigRange.Lines = append(hunk.OrigRange.Lines, &origLine)
+			p.last = []*DiffLine{&newLine, &origLine}
+			p.addedCount++
+			p.removedCount++
+			p.origLeft--
+			p.newLeft--
+		}
+
+		// Lines after a complete hunk, up to the next hunk or file,
+		// are not part of the diff.
+		if p.origLeft <= 0 && p.newLeft <= 0 {
+			p.inHunk = false
+			if err := p.endHunk(); err != nil {
+				return err
 			}
+		}
+	case strings.HasPrefix(l, "--- ") && (file == nil || (p.plain || p.index) && !p.firstHunkInFile):
+		// A plain unified diff, as from "diff -u", has no "diff " line:
+		// each file starts at its "---" line, and is only added to the
+		// diff once a hunk shows it is one.
+		if err := p.endHunk(); err != nil {
+			return err
+		}
+		file = p.startFile(l)
+		p.pending = true
+		p.headerOffset = 3
+		p.plain = true
+		fallthrough
+	case p.firstHunkInFile && strings.HasPrefix(l, "--- "):
+		if name := fileLineName(l[len("--- "):]); name == "/dev/null" || p.index && isSVNNonexistent(l) {
+			file.Mode = New
+			file.OrigName = ""
+		} else if !p.index || unquoteName(name) != file.OrigName {
+			file.OrigName = strings.TrimPrefix(unquoteName(name), p.opts.SrcPrefix)
+		}
+	case p.firstHunkInFile && strings.HasPrefix(l, "+++ "):
+		if p.plain {
+			file.DiffHeader += "\n" + l
+		}
+		if name := fileLineName(l[len("+++ "):]); name == "/dev/null" || p.index && isSVNNonexistent(l) {
+			file.Mode = Deleted
+			file.NewName = ""
+		} else if !p.index || unquoteName(name) != file.NewName {
+			file.NewName = strings.TrimPrefix(unquoteName(name), p.opts.DstPrefix)
+		}
+	case p.firstHunkInFile && l == "GIT binary patch":
+		file.IsBinary = true
+		p.inBinaryPatch = true
+		p.binaryBlocks = 0
+	case p.firstHunkInFile && reBinaryFiles.MatchString(l):
+		file.IsBinary = true
+		m := reBinaryFiles.FindStringSubmatch(l)
+		if m[1] == "/dev/null" {
+			file.Mode = New
+			file.OrigName = ""
+		}
+		if m[2] == "/dev/null" {
+			file.Mode = Deleted
+			file.NewName = ""
+		}
+	case p.firstHunkInFile && strings.HasPrefix(l, "old mode "):
+		file.OldMode = strings.TrimPrefix(l, "old mode ")
+	case p.firstHunkInFile && strings.HasPrefix(l, "new mode "):
+		file.NewMode = strings.TrimPrefix(l, "new mode ")
+	case p.firstHunkInFile && strings.HasPrefix(l, "new file mode "):
+		// An empty new or deleted file has no "---" and "+++" lines to
+		// give its mode. The line is kept to be written back.
+		file.Mode = New
+		file.OrigName = ""
+		file.ExtraHeaders = append(file.ExtraHeaders, l)
+	case p.firstHunkInFile && strings.HasPrefix(l, "deleted file mode "):
+		file.Mode = Deleted
+		file.NewName = ""
+		file.ExtraHeaders = append(file.ExtraHeaders, l)
+	case p.firstHunkInFile && strings.HasPrefix(l, "similarity index "):
+		if n, err := atoi32(strings.TrimSuffix(strings.TrimPrefix(l, "similarity index "), "%")); err == nil {
+			file.Similarity = n
+		}
+		file.ExtraHeaders = append(file.ExtraHeaders, l)
+	case p.firstHunkInFile && strings.HasPrefix(l, "rename from "):
+		file.OrigName = unquoteName(strings.TrimPrefix(l, "rename from "))
+		file.Mode = Renamed
+	case p.firstHunkInFile && strings.HasPrefix(l, "rename to "):
+		file.NewName = unquoteName(strings.TrimPrefix(l, "rename to "))
+		file.Mode = Renamed
+	case p.firstHunkInFile && strings.HasPrefix(l, "copy from "):
+		file.OrigName = unquoteName(strings.TrimPrefix(l, "copy from "))
+		file.Mode = Copied
+	case p.firstHunkInFile && strings.HasPrefix(l, "copy to "):
+		file.NewName = unquoteName(strings.TrimPrefix(l, "copy to "))
+		file.Mode = Copied
+	case p.opts.Strict && !p.firstHunkInFile && isOverflowLine(l):
+		h := p.hunk
+		return fmt.Errorf("hunk %d of %q: header declares %d original and %d new lines, found more: %q",
+			len(file.Chunks), file.name(), h.OrigRange.Length, h.NewRange.Length, l)
+	case strings.HasPrefix(l, "@@ ") || strings.HasPrefix(l, "@@@"):
+		if err := p.endHunk(); err != nil {
+			return err
+		}
+		if p.pending {
+			p.addFile()
+		}
+		if p.firstHunkInFile {
+			p.diffPosCount = 0
+			p.firstHunkInFile = false
+		}
 
-			// (re)set line counts
-			AddedCount = hunk.NewRange.Start
-			RemovedCount = hunk.OrigRange.Start
-		case inHunk && isSourceLine(l):
-			m, err := lineMode(l)
+		// Start new hunk.
+		hunk = &DiffChunk{RawHeader: l, HeaderPosition: p.diffPosCount}
+		p.hunk = hunk
+		file.Chunks = append(file.Chunks, hunk)
+		if strings.HasPrefix(l, "@@@") {
+			return p.startCombinedHunk(hunk, l)
+		}
+
+		// Parse hunk heading for ranges
+		m := reHunk.FindStringSubmatch(l)
+		if len(m) < 5 {
+			return p.errorf(l, "could not parse hunk header")
+		}
+		a, err := atoi32(m[1])
+		if err != nil {
+			return p.errorf(l, "could not parse hunk header")
+		}
+		// An omitted length, or a stray comma with no length, means
+		// a single line.
+		b := 1
+		if len(m[2]) > 0 {
+			b, err = atoi32(m[2])
 			if err != nil {
-				return nil, err
-			}
-			line := DiffLine{
-				Mode:     *m,
-				Content:  l[1:],
-				Position: diffPosCount,
+				return p.errorf(l, "could not parse hunk header")
 			}
-			newLine := line
-			origLine := line
-
-			// add lines to ranges
-			switch *m {
-			case Added:
-				newLine.Number = AddedCount
-				newLine.Content = newLine.Content[1:]
-				hunk.NewRange.Lines = append(hunk.NewRange.Lines, &newLine)
-				hunk.WholeRange.Lines = append(hunk.WholeRange.Lines, &newLine)
-				AddedCount++
-
-			case Removed:
-				origLine.Number = RemovedCount
-				origLine.Content = origLine.Content[1:]
-				hunk.OrigRange.Lines = append(hunk.OrigRange.Lines, &origLine)
-				hunk.WholeRange.Lines = append(hunk.WholeRange.Lines, &origLine)
-				RemovedCount++
-
-			case Unchanged:
-				newLine.Number = AddedCount
-				hunk.NewRange.Lines = append(hunk.NewRange.Lines, &newLine)
-				hunk.WholeRange.Lines = append(hunk.WholeRange.Lines, &newLine)
-				origLine.Number = RemovedCount
-				hunk.OrigRange.Lines = append(hunk.OrigRange.Lines, &origLine)
-				AddedCount++
-				RemovedCount++
+		}
+		c, err := atoi32(m[3])
+		if err != nil {
+			return p.errorf(l, "could not parse hunk header")
+		}
+		d := 1
+		if len(m[4]) > 0 {
+			d, err = atoi32(m[4])
+			if err != nil {
+				return p.errorf(l, "could not parse hunk header")
 			}
 		}
+		if len(m[5]) > 0 {
+			hunk.ChunkHeader = m[5]
+		}
+		if int64(a)+int64(b) > math.MaxInt32 || int64(c)+int64(d) > math.MaxInt32 {
+			return p.errorf(l, "hunk line numbers out of range")
+		}
+
+		// hunk orig range.
+		hunk.OrigRange = DiffRange{
+			Start:  a,
+			Length: b,
+		}
+
+		// hunk new range.
+		hunk.NewRange = DiffRange{
+			Start:  c,
+			Length: d,
+		}
+
+		// (re)set line counts
+		p.addedCount = hunk.NewRange.Start
+		p.removedCount = hunk.OrigRange.Start
+		p.origLeft, p.newLeft = b, d
+		p.inHunk = p.origLeft > 0 || p.newLeft > 0
+	case p.firstHunkInFile && reIndex.MatchString(l):
+		m := reIndex.FindStringSubmatch(l)
+		file.OrigSHA, file.NewSHA, file.IndexMode = m[1], m[2], m[3]
+	case p.firstHunkInFile && l != "":
+		file.ExtraHeaders = append(file.ExtraHeaders, l)
 	}
+	return nil
+}
+
+// errorf returns a ParseError for line, the current line.
+func (p *parser) errorf(line, reason string) error {
+	return &ParseError{Line: p.lineIndex, Text: line, Reason: reason}
+}
 
-	return &diff, nil
+// startFile starts a new modified file headed by line.
+func (p *parser) startFile(line string) *DiffFile {
+	p.file = &DiffFile{DiffHeader: line, Mode: Modified}
+	p.fileStart = p.lineStart
+	p.pending = false
+	p.index = false
+	p.firstHunkInFile = true
+	return p.file
 }
 
+// startContext starts a context format diff at raw, the current line.
+func (p *parser) startContext(raw string) {
+	p.inContextDiff = true
+	p.context.Reset()
+	p.context.WriteString(raw)
+	p.contextStart = p.lineIndex
+	p.contextAt = p.lineStart
+	p.file, p.hunk = nil, nil
+}
+
+// endContext ends the context format diff being read, if any, and adds its
+// files to the diff as ParseContext parses them.
+func (p *parser) endContext() error {
+	if !p.inContextDiff {
+		return nil
+	}
+	p.inContextDiff = false
+	d, err := ParseContext(p.context.String())
+	p.context.Reset()
+	if err != nil {
+		if pe, ok := err.(*ParseError); ok {
+			pe.Line += p.contextStart
+		}
+		return err
+	}
+	if len(d.Files) > 0 && len(p.diff.Files) == 0 {
+		p.diff.UnparsedPrefix = p.prefix.String()[:p.contextAt]
+	}
+	p.diff.Files = append(p.diff.Files, d.Files...)
+	return nil
+}
+
+// end ends the hunk or context format diff being read at the end of the
+// diff.
+func (p *parser) end() error {
+	if err := p.endHunk(); err != nil {
+		return err
+	}
+	return p.endContext()
+}
+
+// addFile adds the current file to the diff. The text before the first
+// file ends where the file starts.
+func (p *parser) addFile() {
+	if len(p.diff.Files) == 0 {
+		p.diff.UnparsedPrefix = p.prefix.String()[:p.fileStart]
+	}
+	p.diff.Files = append(p.diff.Files, p.file)
+	p.pending = false
+}
+
+// extendedHeaderPrefixes start the extended header lines of a git diff
+// that name a file or give its mode.
+var extendedHeaderPrefixes = []string{
+	"old mode ", "new mode ", "new file mode ", "deleted file mode ",
+	"rename from ", "rename to ", "copy from ", "copy to ",
+}
+
+// isExtendedHeaderLine reports whether l is one of git's extended header
+// lines that name a file or give its mode.
+func isExtendedHeaderLine(l string) bool {
+	for _, prefix := range extendedHeaderPrefixes {
+		if strings.HasPrefix(l, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// lineContent splits the content of a hunk line from its terminator when
+// terminators are recorded.
+func (p *parser) lineContent(s string) (content, terminator string) {
+	if !p.opts.RecordTerminators {
+		return s, ""
+	}
+	if strings.HasSuffix(s, "\r") {
+		return strings.TrimSuffix(s, "\r"), "\r\n"
+	}
+	return s, "\n"
+}
+
+// endHunk ends the current hunk, if any. In strict mode it returns an
+// error if the hunk's line counts don't match its header.
+func (p *parser) endHunk() error {
+	origLeft, newLeft := p.origLeft, p.newLeft
+	p.origLeft, p.newLeft = 0, 0
+	if !p.opts.Strict || (origLeft == 0 && newLeft == 0) {
+		return nil
+	}
+	h := p.hunk
+	return fmt.Errorf("hunk %d of %q: header declares %d original and %d new lines, found %d and %d",
+		len(p.file.Chunks), p.file.name(), h.OrigRange.Length, h.NewRange.Length,
+		h.OrigRange.Length-origLeft, h.NewRange.Length-newLeft)
+}
+
+// finish completes the parsed diff after the last line.
+func (p *parser) finish() (*Diff, error) {
+	if err := p.end(); err != nil {
+		return nil, err
+	}
+	if len(p.diff.Files) == 0 {
+		p.diff.UnparsedPrefix = p.prefix.String()
+	}
+	for _, f := range p.diff.Files {
+		f.finish()
+	}
+	return p.diff, nil
+}
+
+// finish sets the parts of the file that are known once it is read whole.
+func (f *DiffFile) finish() {
+	f.IsSymlink = f.hasMode(symlinkMode)
+	f.detectSubmodule()
+}
+
+// isOverflowLine reports whether line, read after a hunk is complete, looks
+// like one more line of the hunk. The "-- " line that ends a mailed patch
+// does not count.
+func isOverflowLine(line string) bool {
+	return line != "-- " && line != "" && strings.IndexByte(" +-", line[0]) >= 0
+}
+
+// isContextDiffLine reports whether line starts a context format ("diff -c")
+// file header or hunk, which Parse hands to ParseContext.
+func isContextDiffLine(line string, inHunk bool) bool {
+	return line == "***************" || (!inHunk && strings.HasPrefix(line, "*** "))
+}
+
+// gitHeaderNames returns the original and new names from a
+// "diff --git <src><orig> <dst><new>" line, less the src and dst prefixes
+// where present. Names holding " <dst>" can make the split ambiguous: the
+// line is split where both sides then name the same path or, failing that,
+// at the only " <dst>" in the line. The "diff --cc <name>" line of a
+// combined diff gives name as both names. Other lines return empty names,
+// and the names are left to the "---"/"+++" or rename lines.
+func gitHeaderNames(line, src, dst string) (origName, newName string) {
+	for _, combined := range []string{"diff --cc ", "diff --combined "} {
+		if strings.HasPrefix(line, combined) {
+			// A combined diff names only the merge result.
+			name := unquoteName(strings.TrimPrefix(line, combined))
+			return name, name
+		}
+	}
+	rest := strings.TrimPrefix(line, "diff --git ")
+	if len(rest) == len(line) {
+		return "", ""
+	}
+	if strings.HasPrefix(rest, `"`) {
+		// Both names are quoted: "a/my file" "b/my file".
+		end := quotedEnd(rest)
+		if end < 0 || !strings.HasPrefix(rest[end:], ` "`) {
+			return "", ""
+		}
+		a, b := unquoteName(rest[:end]), unquoteName(rest[end+1:])
+		if !strings.HasPrefix(a, src) || !strings.HasPrefix(b, dst) {
+			return "", ""
+		}
+		return a[len(src):], b[len(dst):]
+	}
+	// The names are the same length once their prefixes are stripped, so
+	// the separating space is in the middle of what is left.
+	for _, pre := range [][2]string{{src, dst}, {"", ""}} {
+		if !strings.HasPrefix(rest, pre[0]) {
+			continue
+		}
+		names := rest[len(pre[0]):]
+		if len(names) < len(pre[1]) || (len(names)-len(pre[1]))%2 == 0 {
+			continue
+		}
+		mid := (len(names) - len(pre[1])) / 2
+		a, b := names[:mid], names[mid+1:]
+		if names[mid] == ' ' && strings.HasPrefix(b, pre[1]) && a == b[len(pre[1]):] {
+			return a, a
+		}
+	}
+	// Different names, as for a rename, split at the only separator.
+	if !strings.HasPrefix(rest, src) || strings.Count(rest, " "+dst) != 1 {
+		return "", ""
+	}
+	i := strings.Index(rest, " "+dst)
+	return rest[len(src):i], rest[i+1+len(dst):]
+}
+
+// fileLineName returns the path from the rest of a "---" or "+++" line. A
+// tab ends the path: diff tools follow it with a timestamp and git with
+// nothing when the path holds a space. A path git has quoted ends at its
+// closing quote. Colons, as in Windows drive letters, are part of the path.
+func fileLineName(s string) string {
+	if strings.HasPrefix(s, `"`) {
+		if end := quotedEnd(s); end > 0 {
+			return s[:end]
+		}
+	}
+	if i := strings.IndexByte(s, '\t'); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
+
+// isSVNNonexistent reports whether a Subversion "---" or "+++" file line
+// gives the file as missing on its side, as for an added or deleted file.
+func isSVNNonexistent(line string) bool {
+	return strings.HasSuffix(line, "\t(nonexistent)")
+}
+
+// unquoteName decodes a path that git has quoted because it holds special
+// characters, such as "my\tfile" or "\303\251.txt", into the path itself.
+// Other paths are returned as they are.
+func unquoteName(s string) string {
+	if len(s) < 2 || s[0] != '"' || s[len(s)-1] != '"' {
+		return s
+	}
+	// git's C-style escapes are a subset of Go's.
+	if name, err := strconv.Unquote(s); err == nil {
+		return name
+	}
+	return s
+}
+
+// quotedEnd returns the index just after the quoted string that s starts
+// with, or -1 if it is not terminated.
+func quotedEnd(s string) int {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1
+		}
+	}
+	return -1
+}
+
+// atoi32 parses a hunk range number. Numbers are limited to 32 bits so a
+// diff parses the same way whatever the size of int.
+func atoi32(s string) (int, error) {
+	n, err := strconv.ParseInt(s, 10, 32)
+	return int(n), err
+}
+
+// noNewlineMarker follows a line that has no terminating newline.
+const noNewlineMarker = `\ No newline at end of file`
+
 func isSourceLine(line string) bool {
-	if line == `\ No newline at end of file` {
-		return false
+	return line != "" && line != noNewlineMarker
+}
+
+// LineAt returns the hunk and line of the new file's line newLineNumber.
+// It returns false if the line is not in the diff: it is neither added nor
+// context in any hunk.
+func (f *DiffFile) LineAt(newLineNumber int) (*DiffChunk, *DiffLine, bool) {
+	for _, h := range f.Chunks {
+		for _, l := range h.NewRange.Lines {
+			if l.Number == newLineNumber {
+				return h, l, true
+			}
+		}
 	}
-	if l := len(line); l == 0 || (l >= 3 && (line[:3] == "---" || line[:3] == "+++")) {
-		return false
+	return nil, nil, false
+}
+
+// Position returns the position of the new file's line newLineNumber in
+// the file's diff, as GitHub's review comment API expects it, and false if
+// the line is not in the diff. The first "@@" header of the file is
+// position 0 and each line below it counts one: hunk lines, later "@@"
+// headers and "\ No newline at end of file" markers alike. Positions start
+// again at each file.
+func (f *DiffFile) Position(newLineNumber int) (int, bool) {
+	_, l, ok := f.LineAt(newLineNumber)
+	if !ok {
+		return 0, false
 	}
-	return true
+	return l.Position, true
 }
 
-// Length returns the hunks line length
+// Length returns the number of lines of the hunk including its "@@" header
+// line, which is the number of diff positions the hunk takes up. LineCount
+// leaves out the header.
 func (hunk *DiffChunk) Length() int {
 	return len(hunk.WholeRange.Lines) + 1
 }
+
+// LineCount returns the number of lines in the body of the hunk, counting
+// each added, removed and context line once.
+func (hunk *DiffChunk) LineCount() int {
+	return len(hunk.WholeRange.Lines)
+}
+
+// LeadingContext returns the unchanged lines of the hunk before its first
+// added or removed line. If the hunk has no changes all its lines are
+// returned.
+func (hunk *DiffChunk) LeadingContext() []*DiffLine {
+	lines := hunk.WholeRange.Lines
+	for i, l := range lines {
+		if l.Mode != Unchanged {
+			return lines[:i]
+		}
+	}
+	return lines
+}
+
+// TrailingContext returns the unchanged lines of the hunk after its last
+// added or removed line. If the hunk has no changes it returns nil.
+func (hunk *DiffChunk) TrailingContext() []*DiffLine {
+	lines := hunk.WholeRange.Lines
+	for i := len(lines) - 1; i >= 0; i-- {
+		if lines[i].Mode != Unchanged {
+			return lines[i+1:]
+		}
+	}
+	return nil
+}
@@ -4,15 +4,18 @@
 package diffparser
 
 import (
+	"bytes"
+	"fmt"
 	"io/ioutil"
+	"os"
+	"regexp"
+	"strings"
 	"testing"
+	"testing/iotest"
 
 	"github.com/stretchr/testify/require"
 )
 
-// TODO(waigani) tests are missing more creative names (spaces, special
-// chars), and diffed files that are not in the current directory.
-
 func setup(t *testing.T) *Diff {
 	byt, err := ioutil.ReadFile("example.diff")
 	require.NoError(t, err)
@@ -69,53 +72,99 @@ func TestFileModeAndNaming(t *testing.T) {
 	}
 }
 
+func TestModeStrings(t *testing.T) {
+	require.Equal(t, "Deleted", Deleted.String())
+	require.Equal(t, "Copied", fmt.Sprint(Copied))
+	require.Equal(t, "FileMode(9)", FileMode(9).String())
+
+	require.Equal(t, "Added", Added.String())
+	require.Equal(t, "Unchanged", fmt.Sprintf("%v", Unchanged))
+	require.Equal(t, "DiffLineMode(9)", DiffLineMode(9).String())
+}
+
+func TestRangeEnd(t *testing.T) {
+	diff, err := Parse(multiHunkDiff)
+	require.NoError(t, err)
+	require.Equal(t, 4, diff.Files[0].Chunks[0].OrigRange.End())
+	require.Equal(t, 10, diff.Files[0].Chunks[1].NewRange.End())
+
+	// A deletion leaves an empty new range.
+	diff, err = Parse(`diff --git a/file1 b/file1
+index 504d2a1..50ccec3 100644
+--- a/file1
++++ b/file1
+@@ -3,2 +2,0 @@
+-three
+-four
+`)
+	require.NoError(t, err)
+	chunk := diff.Files[0].Chunks[0]
+	require.Equal(t, 4, chunk.OrigRange.End())
+	require.Equal(t, 1, chunk.NewRange.End())
+	require.True(t, chunk.NewRange.End() < chunk.NewRange.Start)
+}
+
 func TestHunk(t *testing.T) {
 	diff := setup(t)
 	expectedOrigLines := []DiffLine{
 		{
-			Mode:     Unchanged,
-			Number:   1,
-			Content:  "some",
-			Position: 2,
+			Mode:       Unchanged,
+			Number:     1,
+			OrigNumber: 1,
+			NewNumber:  2,
+			Content:    "some",
+			Position:   2,
 		}, {
-			Mode:     Unchanged,
-			Number:   2,
-			Content:  "lines",
-			Position: 3,
+			Mode:       Unchanged,
+			Number:     2,
+			OrigNumber: 2,
+			NewNumber:  3,
+			Content:    "lines",
+			Position:   3,
 		}, {
-			Mode:     Removed,
-			Number:   3,
-			Content:  "in",
-			Position: 4,
+			Mode:       Removed,
+			Number:     3,
+			OrigNumber: 3,
+			Content:    "in",
+			Position:   4,
 		}, {
-			Mode:     Unchanged,
-			Number:   4,
-			Content:  "file1",
-			Position: 5,
+			Mode:       Unchanged,
+			Number:     4,
+			OrigNumber: 4,
+			NewNumber:  4,
+			Content:    "file1",
+			Position:   5,
 		},
 	}
 
 	expectedNewLines := []DiffLine{
 		{
-			Mode:     Added,
-			Number:   1,
-			Content:  "add a line",
-			Position: 1,
+			Mode:      Added,
+			Number:    1,
+			NewNumber: 1,
+			Content:   "add a line",
+			Position:  1,
 		}, {
-			Mode:     Unchanged,
-			Number:   2,
-			Content:  "some",
-			Position: 2,
+			Mode:       Unchanged,
+			Number:     2,
+			OrigNumber: 1,
+			NewNumber:  2,
+			Content:    "some",
+			Position:   2,
 		}, {
-			Mode:     Unchanged,
-			Number:   3,
-			Content:  "lines",
-			Position: 3,
+			Mode:       Unchanged,
+			Number:     3,
+			OrigNumber: 2,
+			NewNumber:  3,
+			Content:    "lines",
+			Position:   3,
 		}, {
-			Mode:     Unchanged,
-			Number:   4,
-			Content:  "file1",
-			Position: 5,
+			Mode:       Unchanged,
+			Number:     4,
+			OrigNumber: 4,
+			NewNumber:  4,
+			Content:    "file1",
+			Position:   5,
 		},
 	}
 
@@ -135,3 +184,1808 @@ func TestHunk(t *testing.T) {
 		require.Equal(t, line, *newRange.Lines[i])
 	}
 }
+
+func TestNoNewlineAtFileBoundary(t *testing.T) {
+	diff, err := Parse(`diff --git a/file1 b/file1
+index 504d2a1..50ccec3 100644
+--- a/file1
++++ b/file1
+@@ -1,2 +1,2 @@
+ some
+-lines
+\ No newline at end of file
++text
+\ No newline at end of file
+diff --git a/file2 b/file2
+index c0dafd8..0ccec31 100644
+--- a/file2
++++ b/file2
+@@ -1 +1 @@
+-other
++another
+`)
+	require.NoError(t, err)
+	require.Len(t, diff.Files, 2)
+
+	// The marker belongs to the last hunk of the first file.
+	first := diff.Files[0]
+	require.Len(t, first.Chunks, 1)
+	require.Len(t, first.Chunks[0].WholeRange.Lines, 3)
+	last := first.Chunks[0].WholeRange.Lines[2]
+	require.Equal(t, Added, last.Mode)
+	require.Equal(t, 2, last.Number)
+
+	// The next file starts clean.
+	second := diff.Files[1]
+	require.Equal(t, "file2", second.NewName)
+	require.Len(t, second.Chunks, 1)
+	require.Len(t, second.Chunks[0].WholeRange.Lines, 2)
+	require.Equal(t, 1, second.Chunks[0].WholeRange.Lines[0].Position)
+}
+
+func TestHunkHeaderPosition(t *testing.T) {
+	diff := setup(t)
+	for _, file := range diff.Files {
+		require.Len(t, file.Chunks, 1)
+		require.Equal(t, 0, file.Chunks[0].HeaderPosition)
+	}
+
+	diff, err := Parse(`diff --git a/file1 b/file1
+index 504d2a1..50ccec3 100644
+--- a/file1
++++ b/file1
+@@ -1,2 +1,2 @@
+-one
++uno
+ two
+@@ -10,2 +10,2 @@
+ ten
+-eleven
++once
+`)
+	require.NoError(t, err)
+	chunks := diff.Files[0].Chunks
+	require.Len(t, chunks, 2)
+	require.Equal(t, 0, chunks[0].HeaderPosition)
+	require.Equal(t, 4, chunks[1].HeaderPosition)
+
+	// The second header takes up position 4, so the second hunk's lines
+	// follow it.
+	var positions []int
+	for _, l := range chunks[1].WholeRange.Lines {
+		positions = append(positions, l.Position)
+	}
+	require.Equal(t, []int{5, 6, 7}, positions)
+	pos, ok := diff.Files[0].Position(11)
+	require.True(t, ok)
+	require.Equal(t, 7, pos)
+}
+
+func TestAddedLinesMatching(t *testing.T) {
+	diff, err := Parse(`diff --git a/main.go b/main.go
+index 504d2a1..50ccec3 100644
+--- a/main.go
++++ b/main.go
+@@ -1,3 +1,5 @@
+ package main
+-// TODO remove me
++// TODO handle errors
+ func main() {
++	// TODO log
++	run()
+`)
+	require.NoError(t, err)
+
+	lines := diff.Files[0].AddedLinesMatching(regexp.MustCompile(`TODO`))
+	require.Len(t, lines, 2)
+	require.Equal(t, 2, lines[0].Number)
+	require.Equal(t, 4, lines[1].Number)
+}
+
+func TestLinesOfMode(t *testing.T) {
+	diff, err := Parse(multiHunkDiff)
+	require.NoError(t, err)
+	file := diff.Files[0]
+
+	contents := func(lines []*DiffLine) (c []string, n []int) {
+		for _, l := range lines {
+			c = append(c, l.Content)
+			n = append(n, l.Number)
+		}
+		return c, n
+	}
+	c, n := contents(file.AddedLines())
+	require.Equal(t, []string{"uno", "nueve"}, c)
+	require.Equal(t, []int{1, 9}, n)
+	c, n = contents(file.RemovedLines())
+	require.Equal(t, []string{"one", "nine"}, c)
+	require.Equal(t, []int{1, 9}, n)
+	c, n = contents(file.UnchangedLines())
+	require.Equal(t, []string{"two", "three", "four", "seven", "eight", "ten"}, c)
+	require.Equal(t, []int{2, 3, 4, 7, 8, 10}, n)
+	require.Equal(t, 4, file.UnchangedLines()[1].Position)
+
+	require.Empty(t, setup(t).Files[1].AddedLines())
+}
+
+func TestNewFilesContent(t *testing.T) {
+	diff := setup(t)
+	require.Equal(t, map[string]string{
+		"file4":   "added new file",
+		"newname": "other\nlines\nin\nfile2\n",
+	}, diff.NewFilesContent())
+}
+
+func TestWholeRangeOrder(t *testing.T) {
+	diff, err := Parse(`diff --git a/file1 b/file1
+index 504d2a1..50ccec3 100644
+--- a/file1
++++ b/file1
+@@ -1,7 +1,7 @@
+ one
+-two
+-three
++dos
+ four
++cinco
+-five
+ six
++siete
+-seven
+`)
+	require.NoError(t, err)
+	chunk := diff.Files[0].Chunks[0]
+
+	var got []string
+	for i, l := range chunk.WholeRange.Lines {
+		got = append(got, l.Mode.String()+" "+l.Content)
+		require.Equal(t, i+1, l.Position)
+	}
+	require.Equal(t, []string{
+		"Unchanged one",
+		"Removed two",
+		"Removed three",
+		"Added dos",
+		"Unchanged four",
+		"Added cinco",
+		"Removed five",
+		"Unchanged six",
+		"Added siete",
+		"Removed seven",
+	}, got)
+
+	lines := chunk.WholeRange.Lines
+	require.True(t, lines[1] == chunk.OrigRange.Lines[1])
+	require.True(t, lines[3] == chunk.NewRange.Lines[1])
+	require.True(t, lines[4] == chunk.NewRange.Lines[2])
+}
+
+func TestWholeRangeNumbers(t *testing.T) {
+	diff, err := Parse(`diff --git a/file1 b/file1
+index 504d2a1..50ccec3 100644
+--- a/file1
++++ b/file1
+@@ -3,3 +5,3 @@
+ three
+-four
++cuatro
+ five
+`)
+	require.NoError(t, err)
+
+	var gutters [][2]int
+	for _, l := range diff.Files[0].Chunks[0].WholeRange.Lines {
+		gutters = append(gutters, [2]int{l.OrigNumber, l.NewNumber})
+	}
+	require.Equal(t, [][2]int{{3, 5}, {4, 0}, {0, 6}, {5, 7}}, gutters)
+}
+
+func TestLeadingModeCharsInContent(t *testing.T) {
+	diff, err := Parse(`diff --git a/file1 b/file1
+index 504d2a1..50ccec3 100644
+--- a/file1
++++ b/file1
+@@ -1,6 +1,6 @@
+ +context
+ -context
+  context
+--removed
+-+removed
+- removed
++-added
+++added
++ added
+`)
+	require.NoError(t, err)
+
+	var got []string
+	for _, l := range diff.Files[0].Chunks[0].WholeRange.Lines {
+		got = append(got, l.Content)
+	}
+	require.Equal(t, []string{
+		"+context", "-context", " context",
+		"-removed", "+removed", " removed",
+		"-added", "+added", " added",
+	}, got)
+}
+
+func TestSubmodule(t *testing.T) {
+	diff, err := Parse(`diff --git a/vendor/lib b/vendor/lib
+index 2f1c61a..8e7a8b3 160000
+--- a/vendor/lib
++++ b/vendor/lib
+@@ -1 +1 @@
+-Subproject commit 2f1c61a4d0e8a6a1c6f0f4e0b1d93bbd0f7b1c3e
++Subproject commit 8e7a8b39c0f1b8a1ee3c2d4f5a6b7c8d9e0f1a2b
+diff --git a/file1 b/file1
+index 504d2a1..50ccec3 100644
+--- a/file1
++++ b/file1
+@@ -1,2 +1,2 @@
+-Subproject commit 2f1c61a4d0e8a6a1c6f0f4e0b1d93bbd0f7b1c3e
++Subproject commit 8e7a8b39c0f1b8a1ee3c2d4f5a6b7c8d9e0f1a2b
+ docs
+`)
+	require.NoError(t, err)
+
+	sub := diff.Files[0]
+	require.True(t, sub.IsSubmodule)
+	require.Equal(t, "2f1c61a4d0e8a6a1c6f0f4e0b1d93bbd0f7b1c3e", sub.OrigSubmoduleCommit)
+	require.Equal(t, "8e7a8b39c0f1b8a1ee3c2d4f5a6b7c8d9e0f1a2b", sub.NewSubmoduleCommit)
+
+	require.False(t, diff.Files[1].IsSubmodule)
+	for _, file := range setup(t).Files {
+		require.False(t, file.IsSubmodule)
+	}
+
+	diff, err = Parse(`diff --git a/vendor/new b/vendor/new
+new file mode 160000
+index 0000000..8e7a8b3
+`)
+	require.NoError(t, err)
+	require.True(t, diff.Files[0].IsSubmodule)
+	require.Equal(t, "", diff.Files[0].NewSubmoduleCommit)
+}
+
+func TestParseFile(t *testing.T) {
+	diff, err := ParseFile("example.diff")
+	require.NoError(t, err)
+	require.Equal(t, "", diff.Raw)
+
+	want := setup(t)
+	want.Raw = ""
+	require.Equal(t, want, diff)
+
+	_, err = ParseFile("does-not-exist.diff")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "does-not-exist.diff")
+
+	f, err := ioutil.TempFile("", "diffparser")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	_, err = f.WriteString("@@ -1 +1 @@\n-a\n+b\n")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	_, err = ParseFile(f.Name())
+	require.Error(t, err)
+	require.Contains(t, err.Error(), f.Name())
+}
+
+func TestSymlink(t *testing.T) {
+	diff := setup(t)
+	for _, file := range diff.Files {
+		require.Equal(t, file.OrigName == "symlink", file.IsSymlink, file.name())
+	}
+
+	diff, err := Parse(`diff --git a/link b/link
+old mode 120000
+new mode 100644
+index 03b9162..50ccec3
+--- a/link
++++ b/link
+@@ -1 +1 @@
+-target
+\ No newline at end of file
++contents
+`)
+	require.NoError(t, err)
+	require.True(t, diff.Files[0].IsSymlink)
+}
+
+func TestContextFormatInUnified(t *testing.T) {
+	diff, err := Parse(`diff --git a/file1 b/file1
+index 504d2a1..50ccec3 100644
+--- a/file1
++++ b/file1
+@@ -1,2 +1,2 @@
+-one
++uno
+ two
+diff -c a/file2 b/file2
+*** a/file2	2020-01-01 00:00:00.000000000 +0000
+--- b/file2	2020-01-02 00:00:00.000000000 +0000
+***************
+*** 1,2 ****
+! one
+  two
+--- 1,2 ----
+! uno
+  two
+diff --git a/file3 b/file3
+index 504d2a1..50ccec3 100644
+--- a/file3
++++ b/file3
+@@ -1 +1 @@
+-three
++tres
+`)
+	require.NoError(t, err)
+	require.Len(t, diff.Files, 3)
+
+	require.Equal(t, "file1", diff.Files[0].NewName)
+	require.Len(t, diff.Files[0].Chunks[0].WholeRange.Lines, 3)
+
+	// The context format file is parsed as ParseContext does.
+	file := diff.Files[1]
+	require.Equal(t, "diff -c a/file2 b/file2", file.DiffHeader)
+	require.Equal(t, Modified, file.Mode)
+	require.Equal(t, "file2", file.OrigName)
+	require.Equal(t, "file2", file.NewName)
+	require.Len(t, file.Chunks, 1)
+	lines := file.Chunks[0].WholeRange.Lines
+	require.Len(t, lines, 3)
+	require.Equal(t, "one", file.Chunks[0].OrigRange.Lines[0].Content)
+	require.Equal(t, "uno", file.Chunks[0].NewRange.Lines[0].Content)
+
+	require.Equal(t, "file3", diff.Files[2].NewName)
+	require.Len(t, diff.Files[2].Chunks[0].WholeRange.Lines, 2)
+	require.NoError(t, diff.Validate())
+
+	// A context format diff before any other file, with no "diff" line.
+	diff, err = Parse(`preamble
+*** a/file2	2020-01-01 00:00:00.000000000 +0000
+--- b/file2	2020-01-02 00:00:00.000000000 +0000
+***************
+*** 1 ****
+! one
+--- 1 ----
+! uno
+`)
+	require.NoError(t, err)
+	require.Len(t, diff.Files, 1)
+	require.Equal(t, "preamble\n", diff.UnparsedPrefix)
+	require.Equal(t, "file2", diff.Files[0].NewName)
+	require.Len(t, diff.Files[0].Chunks, 1)
+
+	// Errors give the line in the whole diff.
+	_, err = Parse(`diff --git a/file1 b/file1
+index 504d2a1..50ccec3 100644
+--- a/file1
++++ b/file1
+@@ -1 +1 @@
+-one
++uno
+diff -c a/file2 b/file2
+*** a/file2	2020-01-01 00:00:00.000000000 +0000
++++ b/file2	2020-01-02 00:00:00.000000000 +0000
+`)
+	require.EqualError(t, err, `line 10: expected a "---" file line: "+++ b/file2\t2020-01-02 00:00:00.000000000 +0000"`)
+}
+
+func TestIndexLineHeader(t *testing.T) {
+	for _, index := range []string{
+		"index 504d2a1..50ccec3 100644",
+		"index 504d2a1f3e6d0d9f2c1b3a4e5f60718293a4b5c6..50ccec3a1b2c3d4e5f60718293a4b5c6d7e8f901 100644",
+		"index 504d2a1..50ccec3",
+		"index  504d2a1..50ccec3   100755 ",
+		"index 504d2a1...50ccec3 100644",
+	} {
+		header := "diff --git a/file1 b/file1\n" + index + "\n--- a/file1\n+++ b/file1"
+		diff, err := Parse(header + "\n@@ -1 +1 @@\n-one\n+uno\n")
+		require.NoError(t, err)
+		require.Equal(t, header, diff.Files[0].DiffHeader)
+		require.True(t, strings.HasPrefix(diff.Files[0].OrigSHA, "504d2a1"), index)
+		require.True(t, strings.HasPrefix(diff.Files[0].NewSHA, "50ccec3"), index)
+		require.Empty(t, diff.Files[0].ExtraHeaders, index)
+	}
+}
+
+func TestLargeLineNumbers(t *testing.T) {
+	header := "diff --git a/file1 b/file1\nindex 504d2a1..50ccec3 100644\n--- a/file1\n+++ b/file1\n"
+
+	diff, err := Parse(header + "@@ -2147483640,2 +2147483640,2 @@\n-one\n+uno\n two\n")
+	require.NoError(t, err)
+	lines := diff.Files[0].Chunks[0].WholeRange.Lines
+	require.Equal(t, 2147483640, lines[0].Number)
+	require.Equal(t, 2147483641, lines[2].Number)
+
+	for _, hunk := range []string{
+		"@@ -2147483648,1 +1 @@\n",
+		"@@ -1 +1,2147483648 @@\n",
+		"@@ -2147483647,5 +1 @@\n",
+	} {
+		_, err := Parse(header + hunk)
+		require.Error(t, err, hunk)
+	}
+}
+
+func TestUnifiedLines(t *testing.T) {
+	diff, err := Parse(`diff --git a/file1 b/file1
+index 504d2a1..50ccec3 100644
+--- a/file1
++++ b/file1
+@@ -1,3 +1,3 @@
+ one
+-two
++dos
+ three
+@@ -7,2 +7,3 @@
+ seven
++siete
+ eight
+`)
+	require.NoError(t, err)
+
+	var got []string
+	for _, l := range diff.Files[0].UnifiedLines() {
+		got = append(got, l.Mode.prefix()+l.Content)
+	}
+	require.Equal(t, []string{" one", "-two", "+dos", " three", " seven", "+siete", " eight"}, got)
+}
+
+func TestUnparsedPrefix(t *testing.T) {
+	body := `diff --git a/file1 b/file1
+index 504d2a1..50ccec3 100644
+--- a/file1
++++ b/file1
+@@ -1 +1 @@
+-one
++uno
+`
+	preamble := "difft 0.50.0\n--- a/file1 (1 change)\n+++ b/file1\n\n"
+
+	diff, err := Parse(preamble + body)
+	require.NoError(t, err)
+	require.Equal(t, preamble, diff.UnparsedPrefix)
+	require.Len(t, diff.Files, 1)
+	require.Equal(t, "file1", diff.Files[0].NewName)
+	require.Len(t, diff.Files[0].Chunks[0].WholeRange.Lines, 2)
+
+	diff, err = Parse(body)
+	require.NoError(t, err)
+	require.Empty(t, diff.UnparsedPrefix)
+
+	diff, err = Parse("no diff here\n")
+	require.NoError(t, err)
+	require.Empty(t, diff.Files)
+	require.Equal(t, "no diff here\n", diff.UnparsedPrefix)
+}
+
+func TestPlainUnifiedDiff(t *testing.T) {
+	diff, err := Parse(`--- file1.orig	2020-01-01 00:00:00.000000000 +0000
++++ file1	2020-01-02 00:00:00.000000000 +0000
+@@ -1,2 +1,2 @@
+-one
++uno
+ two
+--- /dev/null	1970-01-01 00:00:00.000000000 +0000
++++ b/dir/file2	2020-01-02 00:00:00.000000000 +0000
+@@ -0,0 +1 @@
++new
+`)
+	require.NoError(t, err)
+	require.Empty(t, diff.UnparsedPrefix)
+	require.Len(t, diff.Files, 2)
+
+	file := diff.Files[0]
+	require.Equal(t, Modified, file.Mode)
+	require.Equal(t, "file1.orig", file.OrigName)
+	require.Equal(t, "file1", file.NewName)
+	require.Equal(t, "--- file1.orig\t2020-01-01 00:00:00.000000000 +0000\n+++ file1\t2020-01-02 00:00:00.000000000 +0000", file.DiffHeader)
+	require.Len(t, file.Chunks[0].WholeRange.Lines, 3)
+
+	file = diff.Files[1]
+	require.Equal(t, New, file.Mode)
+	require.Empty(t, file.OrigName)
+	require.Equal(t, "dir/file2", file.NewName)
+	require.Equal(t, "new", file.Chunks[0].NewRange.Lines[0].Content)
+}
+
+func TestPlainThenGitDiff(t *testing.T) {
+	diff, err := Parse(`--- notes.txt.orig	2020-01-01 00:00:00.000000000 +0000
++++ notes.txt	2020-01-02 00:00:00.000000000 +0000
+@@ -1 +1 @@
+-one
++uno
+diff --git a/bin/run b/bin/run
+old mode 100644
+new mode 100755
+diff --git a/file4 b/file4
+new file mode 100644
+index 0000000..57271b1
+--- /dev/null
++++ b/file4
+@@ -0,0 +1 @@
++added new file
+`)
+	require.NoError(t, err)
+	require.Empty(t, diff.UnparsedPrefix)
+	require.Len(t, diff.Files, 3)
+
+	for i, expected := range []struct {
+		mode             FileMode
+		origName         string
+		newName          string
+		oldMode, newMode string
+		chunks           int
+	}{
+		{Modified, "notes.txt.orig", "notes.txt", "", "", 1},
+		{Modified, "bin/run", "bin/run", "100644", "100755", 0},
+		{New, "", "file4", "", "", 1},
+	} {
+		file := diff.Files[i]
+		require.Equal(t, expected.mode, file.Mode, "file %d", i)
+		require.Equal(t, expected.origName, file.OrigName, "file %d", i)
+		require.Equal(t, expected.newName, file.NewName, "file %d", i)
+		require.Equal(t, expected.oldMode, file.OldMode, "file %d", i)
+		require.Equal(t, expected.newMode, file.NewMode, "file %d", i)
+		require.Len(t, file.Chunks, expected.chunks, "file %d", i)
+	}
+	require.Equal(t, "uno", diff.Files[0].Chunks[0].NewRange.Lines[0].Content)
+	require.Equal(t, []string{"new file mode 100644"}, diff.Files[2].ExtraHeaders)
+}
+
+func TestSVNDiff(t *testing.T) {
+	diff, err := Parse(`Index: src/main.c
+===================================================================
+--- src/main.c	(revision 41)
++++ src/main.c	(working copy)
+@@ -1,2 +1,2 @@
+-one
++uno
+ two
+Index: docs/new.txt
+===================================================================
+--- docs/new.txt	(nonexistent)
++++ docs/new.txt	(working copy)
+@@ -0,0 +1 @@
++new
+Index: a/lib.c
+===================================================================
+--- a/lib.c	(revision 41)
++++ a/lib.c	(working copy)
+@@ -1 +1 @@
+-three
++tres
+`)
+	require.NoError(t, err)
+	require.Empty(t, diff.UnparsedPrefix)
+	require.Len(t, diff.Files, 3)
+
+	file := diff.Files[0]
+	require.Equal(t, Modified, file.Mode)
+	require.Equal(t, "Index: src/main.c", file.DiffHeader)
+	require.Equal(t, "src/main.c", file.OrigName)
+	require.Equal(t, "src/main.c", file.NewName)
+	require.Empty(t, file.ExtraHeaders)
+	require.Len(t, file.Chunks[0].WholeRange.Lines, 3)
+
+	file = diff.Files[1]
+	require.Equal(t, New, file.Mode)
+	require.Empty(t, file.OrigName)
+	require.Equal(t, "docs/new.txt", file.NewName)
+
+	// A path that starts like a git prefix is kept whole.
+	file = diff.Files[2]
+	require.Equal(t, "a/lib.c", file.OrigName)
+	require.Equal(t, "a/lib.c", file.NewName)
+	require.Equal(t, "tres", file.Chunks[0].NewRange.Lines[0].Content)
+
+	// "svn diff --git" adds a git header to each file.
+	diff, err = Parse(`Index: src/main.c
+===================================================================
+diff --git a/src/main.c b/src/main.c
+--- a/src/main.c	(revision 41)
++++ b/src/main.c	(working copy)
+@@ -1 +1 @@
+-one
++uno
+`)
+	require.NoError(t, err)
+	require.Len(t, diff.Files, 1)
+	require.Equal(t, "diff --git a/src/main.c b/src/main.c", diff.Files[0].DiffHeader)
+	require.Equal(t, "src/main.c", diff.Files[0].OrigName)
+	require.Equal(t, "src/main.c", diff.Files[0].NewName)
+}
+
+func TestNoPrefix(t *testing.T) {
+	diff, err := Parse(`diff --git dir/file1 dir/file1
+index 504d2a1..50ccec3 100644
+--- dir/file1
++++ dir/file1
+@@ -1 +1 @@
+-one
++uno
+`)
+	require.NoError(t, err)
+	require.Len(t, diff.Files, 1)
+	require.Equal(t, "dir/file1", diff.Files[0].OrigName)
+	require.Equal(t, "dir/file1", diff.Files[0].NewName)
+}
+
+func TestParseWithOptions(t *testing.T) {
+	diff, err := ParseWithOptions(`diff --git old/file1 new/file1
+index 504d2a1..50ccec3 100644
+--- old/file1
++++ new/file1
+@@ -1 +1 @@
+-one
++uno
+diff --git old/file2 new/file2
+new file mode 100644
+index 0000000..50ccec3
+--- /dev/null
++++ new/file2
+@@ -0,0 +1 @@
++dos
+`, ParseOptions{SrcPrefix: "old/", DstPrefix: "new/"})
+	require.NoError(t, err)
+	require.Len(t, diff.Files, 2)
+	require.Equal(t, "file1", diff.Files[0].OrigName)
+	require.Equal(t, "file1", diff.Files[0].NewName)
+	require.Equal(t, New, diff.Files[1].Mode)
+	require.Equal(t, "file2", diff.Files[1].NewName)
+
+	// Without the options the prefixes are part of the names.
+	diff, err = Parse(diff.Raw)
+	require.NoError(t, err)
+	require.Equal(t, "old/file1", diff.Files[0].OrigName)
+	require.Equal(t, "new/file1", diff.Files[0].NewName)
+}
+
+func TestQuotedNames(t *testing.T) {
+	diff, err := Parse(`diff --git "a/dir/my file\t.txt" "b/dir/my file\t.txt"
+index 504d2a1..50ccec3 100644
+--- "a/dir/my file\t.txt"
++++ "b/dir/my file\t.txt"
+@@ -1 +1 @@
+-one
++uno
+diff --git "a/\303\251.txt" "b/\"quoted\" \\.txt"
+similarity index 100%
+rename from "\303\251.txt"
+rename to "\"quoted\" \\.txt"
+diff --git a/plain name b/plain name
+new file mode 100644
+index 0000000..50ccec3
+--- /dev/null
++++ b/plain name
+@@ -0,0 +1 @@
++dos
+`)
+	require.NoError(t, err)
+	require.Len(t, diff.Files, 3)
+
+	require.Equal(t, "dir/my file\t.txt", diff.Files[0].OrigName)
+	require.Equal(t, "dir/my file\t.txt", diff.Files[0].NewName)
+
+	require.Equal(t, Renamed, diff.Files[1].Mode)
+	require.Equal(t, "é.txt", diff.Files[1].OrigName)
+	require.Equal(t, `"quoted" \.txt`, diff.Files[1].NewName)
+
+	require.Equal(t, "plain name", diff.Files[2].NewName)
+
+	origName, newName := gitHeaderNames(`diff --git "a/my file" "b/my file"`, "a/", "b/")
+	require.Equal(t, "my file", origName)
+	require.Equal(t, "my file", newName)
+}
+
+func TestQuotedGitHeaderOnly(t *testing.T) {
+	// Without "---" and "+++" lines, the names come from the "diff --git"
+	// line alone.
+	input := `diff --git "a/t\tn\nq\"b\\\303\251.sh" "b/t\tn\nq\"b\\\303\251.sh"
+old mode 100644
+new mode 100755
+diff --git "a/old\tname" "b/new\\name"
+similarity index 100%
+rename from "old\tname"
+rename to "new\\name"
+`
+	diff, err := Parse(input)
+	require.NoError(t, err)
+	require.Len(t, diff.Files, 2)
+
+	name := "t\tn\nq\"b\\\u00e9.sh"
+	require.Equal(t, Modified, diff.Files[0].Mode)
+	require.Equal(t, name, diff.Files[0].OrigName)
+	require.Equal(t, name, diff.Files[0].NewName)
+	require.Equal(t, "100755", diff.Files[0].NewMode)
+
+	require.Equal(t, Renamed, diff.Files[1].Mode)
+	require.Equal(t, "old\tname", diff.Files[1].OrigName)
+	require.Equal(t, `new\name`, diff.Files[1].NewName)
+
+	require.Equal(t, input, diff.String())
+}
+
+func TestGitHeaderNames(t *testing.T) {
+	for _, test := range []struct {
+		line, origName, newName string
+	}{
+		{"diff --git a/file1 b/file1", "file1", "file1"},
+		{"diff --git a/old name b/new name", "old name", "new name"},
+		// A path holding " b/" splits where both sides are the same.
+		{"diff --git a/foo b/bar b/foo b/bar", "foo b/bar", "foo b/bar"},
+		// Renames with " b/" in a path are ambiguous.
+		{"diff --git a/foo b/bar b/baz", "", ""},
+		{"diff -u a/file1 b/file1", "", ""},
+	} {
+		origName, newName := gitHeaderNames(test.line, "a/", "b/")
+		require.Equal(t, test.origName, origName, test.line)
+		require.Equal(t, test.newName, newName, test.line)
+	}
+}
+
+func TestHeaderOnlyNames(t *testing.T) {
+	diff, err := Parse(`diff --git a/foo b/bar b/foo b/bar
+old mode 100644
+new mode 100755
+diff --git a/foo b/bar b/baz
+similarity index 100%
+rename from foo b/bar
+rename to baz
+diff --git a/image.png b/image.png
+index 504d2a1..50ccec3 100644
+Binary files a/image.png and b/image.png differ
+`)
+	require.NoError(t, err)
+	require.Len(t, diff.Files, 3)
+
+	require.Equal(t, "foo b/bar", diff.Files[0].OrigName)
+	require.Equal(t, "foo b/bar", diff.Files[0].NewName)
+
+	// The rename lines settle the ambiguous header.
+	require.Equal(t, Renamed, diff.Files[1].Mode)
+	require.Equal(t, "foo b/bar", diff.Files[1].OrigName)
+	require.Equal(t, "baz", diff.Files[1].NewName)
+	require.Equal(t, 100, diff.Files[1].Similarity)
+
+	require.Equal(t, "image.png", diff.Files[2].OrigName)
+	require.Equal(t, "image.png", diff.Files[2].NewName)
+}
+
+func TestEmptyNewAndDeletedFiles(t *testing.T) {
+	input := `diff --git a/empty b/empty
+new file mode 100644
+index 0000000..e69de29
+diff --git a/gone b/gone
+deleted file mode 100644
+index e69de29..0000000
+`
+	diff, err := Parse(input)
+	require.NoError(t, err)
+	require.Len(t, diff.Files, 2)
+
+	require.Equal(t, New, diff.Files[0].Mode)
+	require.Equal(t, "", diff.Files[0].OrigName)
+	require.Equal(t, "empty", diff.Files[0].NewName)
+	require.Empty(t, diff.Files[0].Chunks)
+
+	require.Equal(t, Deleted, diff.Files[1].Mode)
+	require.Equal(t, "gone", diff.Files[1].OrigName)
+	require.Equal(t, "", diff.Files[1].NewName)
+
+	require.NoError(t, diff.Validate())
+	require.Equal(t, input, diff.String())
+}
+
+const noNewlineDiff = `diff --git a/file1 b/file1
+index 504d2a1..50ccec3 100644
+--- a/file1
++++ b/file1
+@@ -1,3 +1,3 @@
+ one
+-two
+-three
+\ No newline at end of file
++dos
++tres
+`
+
+func TestNoNewline(t *testing.T) {
+	diff, err := Parse(noNewlineDiff)
+	require.NoError(t, err)
+	chunk := diff.Files[0].Chunks[0]
+	require.True(t, chunk.OrigRange.Lines[2].NoNewline)
+	require.False(t, chunk.NewRange.Lines[2].NoNewline)
+	require.Len(t, chunk.WholeRange.Lines, 5)
+	// The marker counts as a line of the diff.
+	require.Equal(t, 6, chunk.WholeRange.Lines[4].Position)
+
+	// An unchanged last line is marked on both sides.
+	diff, err = Parse(`diff --git a/file1 b/file1
+index 504d2a1..50ccec3 100644
+--- a/file1
++++ b/file1
+@@ -1,2 +1,2 @@
+-one
++uno
+ two
+\ No newline at end of file
+`)
+	require.NoError(t, err)
+	chunk = diff.Files[0].Chunks[0]
+	require.True(t, chunk.OrigRange.Lines[1].NoNewline)
+	require.True(t, chunk.NewRange.Lines[1].NoNewline)
+	require.Equal(t, diff.Raw, diff.String())
+}
+
+func TestStrict(t *testing.T) {
+	byt, err := ioutil.ReadFile("example.diff")
+	require.NoError(t, err)
+	_, err = ParseWithOptions(string(byt), ParseOptions{Strict: true})
+	require.NoError(t, err)
+	_, err = ParseWithOptions(multiHunkDiff, ParseOptions{Strict: true})
+	require.NoError(t, err)
+
+	short := `diff --git a/file1 b/file1
+index 504d2a1..50ccec3 100644
+--- a/file1
++++ b/file1
+@@ -1,4 +1,5 @@
+ one
+-two
++dos
++tres
+ four
+@@ -9 +10 @@
+-nine
++nueve
+`
+	_, err = ParseWithOptions(short, ParseOptions{Strict: true})
+	require.EqualError(t, err, `hunk 1 of "file1": header declares 4 original and 5 new lines, found 3 and 4`)
+	// A hunk cut short by the end of the diff.
+	_, err = ParseWithOptions(strings.TrimSuffix(multiHunkDiff, " ten\n"), ParseOptions{Strict: true})
+	require.EqualError(t, err, `hunk 2 of "file1": header declares 4 original and 4 new lines, found 3 and 3`)
+
+	long := `diff --git a/file1 b/file1
+index 504d2a1..50ccec3 100644
+--- a/file1
++++ b/file1
+@@ -1 +1 @@
+-one
+-two
++uno
+`
+	_, err = ParseWithOptions(long, ParseOptions{Strict: true})
+	require.EqualError(t, err, `hunk 1 of "file1": header declares 1 original and 1 new lines, found 2 and 1`)
+
+	// Lines after the declared counts are met are extra lines too.
+	extra := `diff --git a/file1 b/file1
+index 504d2a1..50ccec3 100644
+--- a/file1
++++ b/file1
+@@ -1 +1 @@
+-a
++b
++c
++d
+`
+	_, err = ParseWithOptions(extra, ParseOptions{Strict: true})
+	require.EqualError(t, err, `hunk 1 of "file1": header declares 1 original and 1 new lines, found more: "+c"`)
+
+	// A mailed patch's signature is not one.
+	_, err = ParseWithOptions(multiHunkDiff+"-- \n2.30.0\n", ParseOptions{Strict: true})
+	require.NoError(t, err)
+
+	// All are accepted by default.
+	for _, input := range []string{short, long, extra} {
+		_, err = Parse(input)
+		require.NoError(t, err)
+	}
+}
+
+func TestSkipBadLines(t *testing.T) {
+	mangled := `diff --git a/file1 b/file1
+index 504d2a1..50ccec3 100644
+--- a/file1
++++ b/file1
+@@ -1,2 +1,2 @@
+-one
+=20
++uno
+ two
+`
+	_, err := Parse(mangled)
+	require.EqualError(t, err, `line 7: could not parse line mode: "=20"`)
+
+	diff, err := ParseWithOptions(mangled, ParseOptions{SkipBadLines: true})
+	require.NoError(t, err)
+	require.Equal(t, []string{`hunk 1 of "file1": skipped line "=20"`}, diff.ParseWarnings)
+	lines := diff.Files[0].Chunks[0].WholeRange.Lines
+	require.Len(t, lines, 3)
+	require.Equal(t, "uno", lines[1].Content)
+
+	diff, err = Parse(multiHunkDiff)
+	require.NoError(t, err)
+	require.Empty(t, diff.ParseWarnings)
+
+	// A tab marks a context line, and is not kept in its content.
+	tabbed := "diff --git a/file1 b/file1\n--- a/file1\n+++ b/file1\n" +
+		"@@ -1,3 +1,3 @@\n\tone\n-two\n+dos\n\t\tthree\n"
+	_, err = Parse(tabbed)
+	require.EqualError(t, err, `line 5: could not parse line mode: "\tone"`)
+
+	diff, err = ParseWithOptions(tabbed, ParseOptions{SkipBadLines: true, Strict: true})
+	require.NoError(t, err)
+	require.Empty(t, diff.ParseWarnings)
+	lines = diff.Files[0].Chunks[0].WholeRange.Lines
+	require.Len(t, lines, 4)
+	require.Equal(t, Unchanged, lines[0].Mode)
+	require.Equal(t, "one", lines[0].Content)
+	require.Equal(t, 1, lines[0].OrigNumber)
+	require.Equal(t, Unchanged, lines[3].Mode)
+	require.Equal(t, "\tthree", lines[3].Content)
+	require.Equal(t, 3, lines[3].NewNumber)
+}
+
+func TestParseError(t *testing.T) {
+	for _, test := range []struct {
+		diff string
+		want ParseError
+	}{{
+		diff: "diff --git a/file1 b/file1\r\n--- a/file1\r\n+++ b/file1\r\n@@ -x +1 @@\r\n",
+		want: ParseError{Line: 3, Text: "@@ -x +1 @@", Reason: "could not parse hunk header"},
+	}, {
+		diff: "diff --git a/file1 b/file1\n--- a/file1\n+++ b/file1\n@@ -1 +2147483647 @@\n",
+		want: ParseError{Line: 3, Text: "@@ -1 +2147483647 @@", Reason: "hunk line numbers out of range"},
+	}, {
+		diff: "diff --git a/file1 b/file1\n--- a/file1\n+++ b/file1\n@@ -1 +1 @@\n*one\n",
+		want: ParseError{Line: 4, Text: "*one", Reason: "could not parse line mode"},
+	}, {
+		diff: "diff --cc file1\n--- a/file1\n+++ b/file1\n@@@ -1 +1 @@@\n",
+		want: ParseError{Line: 3, Text: "@@@ -1 +1 @@@", Reason: "could not parse hunk header"},
+	}} {
+		_, err := Parse(test.diff)
+		perr, ok := err.(*ParseError)
+		require.True(t, ok, "%v", err)
+		require.Equal(t, test.want, *perr)
+	}
+}
+
+func TestNoFileHeader(t *testing.T) {
+	for _, input := range []string{"", "   \n\n", "\t"} {
+		diff, err := Parse(input)
+		require.NoError(t, err)
+		require.Empty(t, diff.Files)
+		require.Equal(t, input, diff.UnparsedPrefix)
+	}
+
+	_, err := Parse("@@ -1 +1 @@\n")
+	require.EqualError(t, err, `line 1: hunk before any file header: "@@ -1 +1 @@"`)
+	_, err = Parse("some text\n@@ -1 +1 @@\n-one\n+uno\n")
+	require.EqualError(t, err, `line 2: hunk before any file header: "@@ -1 +1 @@"`)
+
+	// Lines that only look like hunk lines may be text, such as a list in
+	// a commit message.
+	diff, err := Parse("+added\n")
+	require.NoError(t, err)
+	require.Empty(t, diff.Files)
+	require.Equal(t, "+added\n", diff.UnparsedPrefix)
+}
+
+func TestAddedRanges(t *testing.T) {
+	diff, err := Parse(`diff --git a/file1 b/file1
+index 504d2a1..50ccec3 100644
+--- a/file1
++++ b/file1
+@@ -1,3 +1,7 @@
++zero
+ one
++uno
++dos
+ two
+ three
++tres
+@@ -9,1 +12,2 @@
+ nine
++nueve
+`)
+	require.NoError(t, err)
+	require.Equal(t, [][2]int{{1, 1}, {3, 4}, {7, 7}, {13, 13}}, diff.Files[0].AddedRanges())
+
+	require.Equal(t, [][2]int{{1, 4}}, setup(t).Files[4].AddedRanges())
+}
+
+func TestDuplicateFiles(t *testing.T) {
+	diff, err := Parse(`diff --git a/file1 b/file1
+index 504d2a1..50ccec3 100644
+--- a/file1
++++ b/file1
+@@ -1 +1,2 @@
+ one
++two
+diff --git a/file2 b/file2
+index 504d2a1..50ccec3 100644
+--- a/file2
++++ b/file2
+@@ -1 +1 @@
+-a
++b
+diff --git a/file1 b/file1
+index 50ccec3..60ddfd4 100644
+--- a/file1
++++ b/file1
+@@ -5 +5,2 @@
+ five
++six
+`)
+	require.NoError(t, err)
+
+	files := diff.FilesFor("file1")
+	require.Len(t, files, 2)
+	require.Equal(t, diff.Files[0], files[0])
+	require.Equal(t, diff.Files[2], files[1])
+	require.Empty(t, diff.FilesFor("missing"))
+
+	require.Equal(t, []int{2, 6}, diff.Changed()["file1"])
+}
+
+func TestWalk(t *testing.T) {
+	diff, err := Parse(multiHunkDiff + `diff --git a/file2 b/file2
+index 504d2a1..50ccec3 100644
+--- a/file2
++++ b/file2
+@@ -1 +1 @@
+-a
++b
+`)
+	require.NoError(t, err)
+
+	var got []string
+	diff.Walk(func(f *DiffFile, h *DiffChunk, l *DiffLine) {
+		got = append(got, f.NewName+" "+h.ChunkHeader+" "+l.Mode.prefix()+l.Content)
+	})
+	require.Equal(t, []string{
+		"file1 first -one", "file1 first +uno", "file1 first  two", "file1 first  three", "file1 first  four",
+		"file1 second  seven", "file1 second  eight", "file1 second -nine", "file1 second +nueve", "file1 second  ten",
+		"file2  -a", "file2  +b",
+	}, got)
+}
+
+func TestGetFile(t *testing.T) {
+	diff := setup(t)
+
+	require.Equal(t, diff.Files[1], diff.GetFile("file2"))
+	require.Equal(t, diff.Files[4], diff.GetFile("newname"))
+	require.Nil(t, diff.GetFile("missing"))
+
+	// Deleted files have no new name, new files no original name.
+	require.Equal(t, diff.Files[4], diff.GetFileByNewName("newname"))
+	require.Nil(t, diff.GetFileByNewName("file2"))
+	require.Equal(t, diff.Files[1], diff.GetFileByOrigName("file2"))
+	require.Nil(t, diff.GetFileByOrigName("newname"))
+
+	diff, err := Parse(renameDiff)
+	require.NoError(t, err)
+	file := diff.Files[0]
+	require.Equal(t, file, diff.GetFile(file.OrigName))
+	require.Equal(t, file, diff.GetFile(file.NewName))
+	require.Nil(t, diff.GetFileByNewName(file.OrigName))
+}
+
+func TestRawHeader(t *testing.T) {
+	diff, err := Parse(`diff --git a/file1 b/file1
+index 504d2a1..50ccec3 100644
+--- a/file1
++++ b/file1
+@@ -1 +1 @@ func main() {
+-one
++uno
+`)
+	require.NoError(t, err)
+	chunk := diff.Files[0].Chunks[0]
+	require.Equal(t, "@@ -1 +1 @@ func main() {", chunk.RawHeader)
+	require.Equal(t, "func main() {", chunk.ChunkHeader)
+	require.Equal(t, diff.Raw, diff.String())
+
+	// Without the raw header it is made from the ranges.
+	chunk.RawHeader = ""
+	require.Equal(t, "@@ -1,1 +1,1 @@ func main() {\n-one\n+uno\n", chunk.String())
+}
+
+func TestLineAt(t *testing.T) {
+	diff, err := Parse(multiHunkDiff)
+	require.NoError(t, err)
+	file := diff.Files[0]
+
+	chunk, line, ok := file.LineAt(1)
+	require.True(t, ok)
+	require.Equal(t, file.Chunks[0], chunk)
+	require.Equal(t, Added, line.Mode)
+	require.Equal(t, "uno", line.Content)
+
+	chunk, line, ok = file.LineAt(10)
+	require.True(t, ok)
+	require.Equal(t, file.Chunks[1], chunk)
+	require.Equal(t, Unchanged, line.Mode)
+	require.Equal(t, "ten", line.Content)
+	require.Equal(t, 11, line.Position)
+
+	// Line 5 is between the hunks.
+	_, _, ok = file.LineAt(5)
+	require.False(t, ok)
+	_, _, ok = file.LineAt(11)
+	require.False(t, ok)
+}
+
+func TestPosition(t *testing.T) {
+	diff, err := Parse(multiHunkDiff + `diff --git a/file2 b/file2
+index 504d2a1..50ccec3 100644
+--- a/file2
++++ b/file2
+@@ -1 +1,2 @@
+ one
++two
+`)
+	require.NoError(t, err)
+
+	for _, test := range []struct {
+		file, line, position int
+	}{
+		{0, 1, 2},  // +uno, below -one at 1
+		{0, 4, 5},  // four
+		{0, 7, 7},  // seven, below the second header at 6
+		{0, 9, 10}, // +nueve
+		{1, 2, 2},  // +two, counted from file2's own header
+	} {
+		pos, ok := diff.Files[test.file].Position(test.line)
+		require.True(t, ok)
+		require.Equal(t, test.position, pos, "file %d line %d", test.file, test.line)
+	}
+
+	_, ok := diff.Files[0].Position(5)
+	require.False(t, ok)
+}
+
+func TestLength(t *testing.T) {
+	diff := setup(t)
+	for i, lines := range []int{5, 4, 4, 1, 4, 1} {
+		hunk := diff.Files[i].Chunks[0]
+		require.Equal(t, lines, hunk.LineCount(), "file %d", i)
+		require.Equal(t, lines+1, hunk.Length(), "file %d", i)
+	}
+
+	// Length counts the header, so a hunk's positions end where the next
+	// hunk's header is.
+	diff, err := Parse(multiHunkDiff)
+	require.NoError(t, err)
+	chunks := diff.Files[0].Chunks
+	require.Equal(t, chunks[1].HeaderPosition, chunks[0].HeaderPosition+chunks[0].Length())
+}
+
+func TestChangedDetailed(t *testing.T) {
+	diff, err := Parse(`diff --git a/file1 b/file1
+index 504d2a1..50ccec3 100644
+--- a/file1
++++ b/file1
+@@ -1,3 +1,3 @@
+ one
+-two
++dos
+ three
+diff --git a/gone b/gone
+deleted file mode 100644
+index 504d2a1..0000000
+--- a/gone
++++ /dev/null
+@@ -1,2 +0,0 @@
+-a
+-b
+`)
+	require.NoError(t, err)
+
+	changed := diff.ChangedDetailed()
+	require.Len(t, changed, 2)
+	require.Equal(t, &ChangedLines{Added: []int{2}, Removed: []int{2}}, changed["file1"])
+	require.Equal(t, &ChangedLines{Removed: []int{1, 2}}, changed["gone"])
+
+	// Changed still ignores deleted files.
+	require.Equal(t, map[string][]int{"file1": {2}}, diff.Changed())
+}
+
+func TestFunctionContext(t *testing.T) {
+	diff, err := Parse("diff --git a/main.go b/main.go\n" +
+		"index fb64a71..6f00796 100644\n" +
+		"--- a/main.go\n" +
+		"+++ b/main.go\n" +
+		"@@ -5,7 +5,7 @@ import \"fmt\"\n" +
+		" func helper(n int) int {\n" +
+		" \ttotal := 0\n" +
+		" \tfor i := 0; i < n; i++ {\n" +
+		"-\t\ttotal += i\n" +
+		"+\t\ttotal += i * 2\n" +
+		" \t}\n" +
+		" \treturn total\n" +
+		" }\n" +
+		"@@ -13,7 +13,7 @@ func helper(n int) int {\n" +
+		" func main() {\n" +
+		" \tfmt.Println(\"start\")\n" +
+		" \tx := helper(10)\n" +
+		" \n" +
+		" \tfmt.Println(x)\n" +
+		"-\tfmt.Println(\"end\")\n" +
+		"+\tfmt.Println(\"done\")\n" +
+		" }\n")
+	require.NoError(t, err)
+
+	chunks := diff.Files[0].Chunks
+	require.Len(t, chunks, 2)
+	require.Equal(t, `import "fmt"`, chunks[0].ChunkHeader)
+	require.Equal(t, "func helper(n int) int {", chunks[1].ChunkHeader)
+	require.Len(t, chunks[1].OrigRange.Lines, 7)
+	require.Len(t, chunks[1].NewRange.Lines, 7)
+	require.Equal(t, "", chunks[1].NewRange.Lines[3].Content)
+	require.Equal(t, 19, chunks[1].NewRange.Lines[6].Number)
+}
+
+func TestWideHunk(t *testing.T) {
+	const n = 100000
+	var b strings.Builder
+	b.WriteString("diff --git a/big.go b/big.go\n--- a/big.go\n+++ b/big.go\n")
+	b.WriteString(fmt.Sprintf("@@ -1,%d +1,%d @@ func big() {\n", n, n+1))
+	for i := 0; i < n; i++ {
+		if i == n/2 {
+			b.WriteString("+\tinserted()\n")
+		}
+		b.WriteString(" \tline()\n")
+	}
+
+	diff, err := Parse(b.String())
+	require.NoError(t, err)
+	chunk := diff.Files[0].Chunks[0]
+	require.Equal(t, "func big() {", chunk.ChunkHeader)
+	require.Len(t, chunk.WholeRange.Lines, n+1)
+	require.Equal(t, [][2]int{{n/2 + 1, n/2 + 1}}, diff.Files[0].AddedRanges())
+}
+
+func TestCRLF(t *testing.T) {
+	byt, err := ioutil.ReadFile("example.diff")
+	require.NoError(t, err)
+	lf := string(byt) + "\n"
+
+	// A CRLF terminated diff of LF files parses like the LF diff.
+	expected, err := Parse(lf)
+	require.NoError(t, err)
+	diff, err := Parse(strings.Replace(lf, "\n", "\r\n", -1))
+	require.NoError(t, err)
+	expected.Raw = diff.Raw
+	require.Equal(t, expected, diff)
+
+	// An LF terminated diff of a CRLF file keeps the "\r" in the content.
+	diff, err = Parse("diff --git a/file1 b/file1\n" +
+		"index 504d2a1..50ccec3 100644\n" +
+		"--- a/file1\n" +
+		"+++ b/file1\n" +
+		"@@ -1,2 +1,2 @@\n" +
+		" one\r\n" +
+		"-two\r\n" +
+		"+dos\r\n")
+	require.NoError(t, err)
+	lines := diff.Files[0].Chunks[0].WholeRange.Lines
+	require.Equal(t, "file1", diff.Files[0].NewName)
+	require.Equal(t, "one\r", lines[0].Content)
+	require.Equal(t, "dos\r", lines[2].Content)
+
+	// A CRLF terminated diff of a CRLF file keeps one "\r".
+	diff, err = Parse("diff --git a/file1 b/file1\r\n" +
+		"--- a/file1\r\n" +
+		"+++ b/file1\r\n" +
+		"@@ -1 +1 @@\r\n" +
+		"-two\r\r\n" +
+		"+dos\r\r\n")
+	require.NoError(t, err)
+	lines = diff.Files[0].Chunks[0].WholeRange.Lines
+	require.Equal(t, "two\r", lines[0].Content)
+	require.Equal(t, "dos\r", lines[1].Content)
+}
+
+func TestWindowsPaths(t *testing.T) {
+	for _, test := range []struct {
+		orig, new string
+		name      string
+	}{
+		{"--- a/C:/src/main.go", "+++ b/C:/src/main.go", "C:/src/main.go"},
+		{`--- a/C:\src\main.go`, `+++ b/C:\src\main.go`, `C:\src\main.go`},
+		{"--- a/C:/src/main.go\t2020-01-01 10:00:00.000000000 +0100",
+			"+++ b/C:/src/main.go\t2020-01-02 11:30:00.000000000 +0100", "C:/src/main.go"},
+		{"--- a/my file.go\t", "+++ b/my file.go\t", "my file.go"},
+	} {
+		diff, err := Parse("diff --git a/x b/x\n" + test.orig + "\n" + test.new + "\n@@ -1 +1 @@\n-a\n+b\n")
+		require.NoError(t, err)
+		require.Equal(t, test.name, diff.Files[0].OrigName)
+		require.Equal(t, test.name, diff.Files[0].NewName)
+	}
+}
+
+func TestExtraHeaders(t *testing.T) {
+	diff, err := Parse(`diff --git a/file1 b/file1
+x-future-extension: value
+index 504d2a1..50ccec3 100644
+--- a/file1
++++ b/file1
+@@ -1 +1 @@
+-one
++uno
+diff --git a/file2 b/file2
+index 504d2a1..50ccec3 100644
+--- a/file2
++++ b/file2
+@@ -1 +1 @@
+-two
++dos
+`)
+	require.NoError(t, err)
+	require.Equal(t, []string{"x-future-extension: value"}, diff.Files[0].ExtraHeaders)
+	require.Empty(t, diff.Files[1].ExtraHeaders)
+
+	diff = setup(t)
+	require.Empty(t, diff.Files[0].ExtraHeaders)
+	require.Equal(t, []string{"deleted file mode 100644"}, diff.Files[1].ExtraHeaders)
+}
+
+func TestCoverageOverlap(t *testing.T) {
+	file := setup(t).Files[4]
+
+	covered, uncovered := file.CoverageOverlap([]int{2, 4, 9})
+	require.Equal(t, []int{2, 4}, covered)
+	require.Equal(t, []int{1, 3}, uncovered)
+
+	covered, uncovered = file.CoverageOverlap(nil)
+	require.Empty(t, covered)
+	require.Equal(t, []int{1, 2, 3, 4}, uncovered)
+}
+
+func TestOmittedRangeLength(t *testing.T) {
+	header := "diff --git a/file1 b/file1\nindex 504d2a1..50ccec3 100644\n--- a/file1\n+++ b/file1\n"
+	for _, hunk := range []string{
+		"@@ -5 +5 @@\n",
+		"@@ -5, +5, @@\n",
+	} {
+		diff, err := Parse(header + hunk + "-five\n+cinco\n")
+		require.NoError(t, err, hunk)
+		chunk := diff.Files[0].Chunks[0]
+		require.Equal(t, DiffRange{Start: 5, Length: 1, Lines: chunk.OrigRange.Lines}, chunk.OrigRange, hunk)
+		require.Equal(t, DiffRange{Start: 5, Length: 1, Lines: chunk.NewRange.Lines}, chunk.NewRange, hunk)
+		require.Equal(t, 5, chunk.NewRange.Lines[0].Number, hunk)
+	}
+}
+
+func TestRecordTerminators(t *testing.T) {
+	raw := "diff --git a/file1 b/file1\n" +
+		"index 504d2a1..50ccec3 100644\n" +
+		"--- a/file1\n" +
+		"+++ b/file1\n" +
+		"@@ -1,3 +1,3 @@\n" +
+		" one\r\n" +
+		"-two\r\n" +
+		"+dos\n" +
+		" three\r\n" +
+		"\\ No newline at end of file\n"
+	diff, err := ParseWithOptions(raw, ParseOptions{RecordTerminators: true})
+	require.NoError(t, err)
+	lines := diff.Files[0].Chunks[0].WholeRange.Lines
+	var got [][2]string
+	for _, l := range lines {
+		got = append(got, [2]string{l.Content, l.Terminator})
+	}
+	require.Equal(t, [][2]string{{"one", "\r\n"}, {"two", "\r\n"}, {"dos", "\n"}, {"three\r", ""}}, got)
+	require.Equal(t, raw, diff.String())
+
+	content, err := diff.Files[0].Apply("one\r\ntwo\r\nthree\r")
+	require.NoError(t, err)
+	require.Equal(t, "one\r\ndos\nthree\r", content)
+
+	// By default the "\r" is content.
+	diff, err = Parse(raw)
+	require.NoError(t, err)
+	lines = diff.Files[0].Chunks[0].WholeRange.Lines
+	require.Equal(t, "one\r", lines[0].Content)
+	require.Empty(t, lines[0].Terminator)
+}
+
+func TestTrailingGarbage(t *testing.T) {
+	diff, err := Parse(`From 2f1c61a4d0e8a6a1c6f0f4e0b1d93bbd0f7b1c3e Mon Sep 17 00:00:00 2001
+Subject: [PATCH] change file1
+
+diff --git a/file1 b/file1
+index 504d2a1..50ccec3 100644
+--- a/file1
++++ b/file1
+@@ -1,2 +1,2 @@
+-one
++uno
+ two
+-- 
+2.39.5
+
+`)
+	require.NoError(t, err)
+	require.Len(t, diff.Files, 1)
+	lines := diff.Files[0].Chunks[0].WholeRange.Lines
+	require.Len(t, lines, 3)
+	require.Equal(t, "two", lines[2].Content)
+	require.Empty(t, diff.Files[0].ExtraHeaders)
+}
+
+func TestHunkLinesLookingLikeHeaders(t *testing.T) {
+	diff, err := Parse(`diff --git a/file1 b/file1
+index 504d2a1..50ccec3 100644
+--- a/file1
++++ b/file1
+@@ -1,3 +1,3 @@
+--- a/other
++++ b/other
+ --- /dev/null
+-last
++final
+`)
+	require.NoError(t, err)
+	file := diff.Files[0]
+	require.Equal(t, Modified, file.Mode)
+	require.Equal(t, "file1", file.OrigName)
+	require.Equal(t, "file1", file.NewName)
+
+	var got []string
+	for _, l := range file.Chunks[0].WholeRange.Lines {
+		got = append(got, l.Mode.prefix()+l.Content)
+	}
+	require.Equal(t, []string{"--- a/other", "+++ b/other", " --- /dev/null", "-last", "+final"}, got)
+}
+
+func TestHunkContext(t *testing.T) {
+	diff, err := Parse(multiHunkDiff)
+	require.NoError(t, err)
+	chunks := diff.Files[0].Chunks
+
+	require.Empty(t, chunks[0].LeadingContext())
+	trailing := chunks[0].TrailingContext()
+	require.Len(t, trailing, 3)
+	require.Equal(t, "two", trailing[0].Content)
+
+	leading := chunks[1].LeadingContext()
+	require.Len(t, leading, 2)
+	require.Equal(t, "seven", leading[0].Content)
+	require.Equal(t, "eight", leading[1].Content)
+	trailing = chunks[1].TrailingContext()
+	require.Len(t, trailing, 1)
+	require.Equal(t, "ten", trailing[0].Content)
+}
+
+const renameDiff = `diff --git a/old/name.go b/new/name.go
+similarity index 100%
+rename from old/name.go
+rename to new/name.go
+diff --git a/old/edited.go b/new/edited.go
+similarity index 80%
+rename from old/edited.go
+rename to new/edited.go
+index 504d2a1..50ccec3 100644
+--- a/old/edited.go
++++ b/new/edited.go
+@@ -1,2 +1,2 @@
+ package name
+-var x = 1
++var x = 2
+`
+
+func TestRenames(t *testing.T) {
+	diff, err := Parse(renameDiff)
+	require.NoError(t, err)
+	require.Len(t, diff.Files, 2)
+
+	pure := diff.Files[0]
+	require.Equal(t, Renamed, pure.Mode)
+	require.Equal(t, "old/name.go", pure.OrigName)
+	require.Equal(t, "new/name.go", pure.NewName)
+	require.Empty(t, pure.Chunks)
+	require.Equal(t, []string{"similarity index 100%"}, pure.ExtraHeaders)
+
+	edited := diff.Files[1]
+	require.Equal(t, Renamed, edited.Mode)
+	require.Equal(t, "old/edited.go", edited.OrigName)
+	require.Equal(t, "new/edited.go", edited.NewName)
+	require.Len(t, edited.Chunks, 1)
+	require.Equal(t, []int{2}, diff.Changed()["new/edited.go"])
+
+	result, err := diff.ApplyTo(map[string]string{
+		"old/name.go":   "package name\n",
+		"old/edited.go": "package name\nvar x = 1\n",
+	})
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{
+		"new/name.go":   "package name\n",
+		"new/edited.go": "package name\nvar x = 2\n",
+	}, result)
+}
+
+func TestBareRenameHeader(t *testing.T) {
+	fragment := `From the review:
+rename from old/name.go
+rename to new/name.go
+--- a/old/name.go
++++ b/new/name.go
+@@ -1 +1 @@
+-var x = 1
++var x = 2
+`
+	// By default the rename lines are skipped, and the file starts at its
+	// "---" line.
+	diff, err := Parse(fragment)
+	require.NoError(t, err)
+	require.Len(t, diff.Files, 1)
+	require.Equal(t, Modified, diff.Files[0].Mode)
+	require.Equal(t, "From the review:\nrename from old/name.go\nrename to new/name.go\n", diff.UnparsedPrefix)
+
+	diff, err = ParseWithOptions(fragment, ParseOptions{SkipBadLines: true})
+	require.NoError(t, err)
+	require.Len(t, diff.Files, 1)
+	require.Equal(t, "From the review:\n", diff.UnparsedPrefix)
+	f := diff.Files[0]
+	require.Equal(t, Renamed, f.Mode)
+	require.Equal(t, "old/name.go", f.OrigName)
+	require.Equal(t, "new/name.go", f.NewName)
+	require.Equal(t, "rename from old/name.go", f.DiffHeader)
+	require.Len(t, f.Chunks, 1)
+	require.Equal(t, "var x = 2", f.Chunks[0].NewRange.Lines[0].Content)
+
+	diff, err = ParseWithOptions("old mode 100644\nnew mode 100755\n", ParseOptions{SkipBadLines: true})
+	require.NoError(t, err)
+	require.Len(t, diff.Files, 1)
+	require.Equal(t, "100644", diff.Files[0].OldMode)
+	require.Equal(t, "100755", diff.Files[0].NewMode)
+}
+
+func TestCopies(t *testing.T) {
+	diff, err := Parse(`diff --git a/src/a.go b/src/b.go
+similarity index 100%
+copy from src/a.go
+copy to src/b.go
+diff --git a/src/a.go b/src/c.go
+similarity index 75%
+copy from src/a.go
+copy to src/c.go
+index 504d2a1..50ccec3 100644
+--- a/src/a.go
++++ b/src/c.go
+@@ -1 +1 @@
+-package a
++package c
+`)
+	require.NoError(t, err)
+	require.Len(t, diff.Files, 2)
+
+	for i, expected := range []struct {
+		newName string
+		chunks  int
+	}{
+		{newName: "src/b.go", chunks: 0},
+		{newName: "src/c.go", chunks: 1},
+	} {
+		file := diff.Files[i]
+		require.Equal(t, Copied, file.Mode)
+		require.Equal(t, "src/a.go", file.OrigName)
+		require.Equal(t, expected.newName, file.NewName)
+		require.Len(t, file.Chunks, expected.chunks)
+	}
+
+	result, err := diff.ApplyTo(map[string]string{"src/a.go": "package a\n"})
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{
+		"src/a.go": "package a\n",
+		"src/b.go": "package a\n",
+		"src/c.go": "package c\n",
+	}, result)
+}
+
+func TestModeChange(t *testing.T) {
+	diff, err := Parse(`diff --git a/bin/run b/bin/run
+old mode 100644
+new mode 100755
+diff --git a/dir with space/x.sh b/dir with space/x.sh
+old mode 100755
+new mode 100644
+index 504d2a1..50ccec3
+--- a/dir with space/x.sh
++++ b/dir with space/x.sh
+@@ -1 +1 @@
+-echo hi
++echo hello
+`)
+	require.NoError(t, err)
+	require.Len(t, diff.Files, 2)
+
+	for i, expected := range []struct {
+		name             string
+		oldMode, newMode string
+		chunks           int
+	}{
+		{name: "bin/run", oldMode: "100644", newMode: "100755", chunks: 0},
+		{name: "dir with space/x.sh", oldMode: "100755", newMode: "100644", chunks: 1},
+	} {
+		file := diff.Files[i]
+		require.Equal(t, Modified, file.Mode)
+		require.Equal(t, expected.name, file.OrigName)
+		require.Equal(t, expected.name, file.NewName)
+		require.Equal(t, expected.oldMode, file.OldMode)
+		require.Equal(t, expected.newMode, file.NewMode)
+		require.Len(t, file.Chunks, expected.chunks)
+		require.Empty(t, file.ExtraHeaders)
+	}
+}
+
+func TestIndexLine(t *testing.T) {
+	diff := setup(t)
+	for i, expected := range []struct {
+		origSHA, newSHA, mode string
+	}{
+		{"504d2a1", "50ccec3", "100644"},
+		{"c0dafd8", "0000000", ""},
+		{"576bba8", "0000000", ""},
+		{"0000000", "57271b1", ""},
+		{"0000000", "c0dafd8", ""},
+		{"03b9162", "0000000", ""},
+	} {
+		file := diff.Files[i]
+		require.Equal(t, expected.origSHA, file.OrigSHA, "file %d", i)
+		require.Equal(t, expected.newSHA, file.NewSHA, "file %d", i)
+		require.Equal(t, expected.mode, file.IndexMode, "file %d", i)
+		require.Equal(t, expected.origSHA == "0000000", file.IsNewBlob(), "file %d", i)
+		require.Equal(t, expected.newSHA == "0000000", file.IsDeletedBlob(), "file %d", i)
+	}
+
+	diff, err := Parse(`diff --git a/new.txt b/new.txt
+index 0000000000000000000000000000000000000000..57271b1c9a4e3b6ad1f0c5b71c6d2b2f7b5e1a4d
+diff --git a/gone.txt b/gone.txt
+index c0dafd8..0000000
+diff --git a/kept.txt b/kept.txt
+index 0a0b0c0..0000001 100644
+`)
+	require.NoError(t, err)
+	require.Len(t, diff.Files, 3)
+	require.True(t, diff.Files[0].IsNewBlob())
+	require.False(t, diff.Files[0].IsDeletedBlob())
+	require.False(t, diff.Files[1].IsNewBlob())
+	require.True(t, diff.Files[1].IsDeletedBlob())
+	require.False(t, diff.Files[2].IsNewBlob())
+	require.False(t, diff.Files[2].IsDeletedBlob())
+	require.False(t, (&DiffFile{}).IsNewBlob())
+}
+
+func TestBinaryFiles(t *testing.T) {
+	diff, err := Parse(`diff --git a/logo.png b/logo.png
+index 504d2a1..50ccec3 100644
+Binary files a/logo.png and b/logo.png differ
+diff --git a/new.png b/new.png
+new file mode 100644
+index 0000000..50ccec3
+Binary files /dev/null and b/new.png differ
+diff --git a/icon.png b/icon.png
+index 504d2a1..50ccec3 100644
+GIT binary patch
+literal 12
+TcmZ?wbhEHbRA6LaU|;|M1^@*B
+
+literal 10
+RcmZ?wbhEHbRA6La00001
+
+diff --git a/file1 b/file1
+index 504d2a1..50ccec3 100644
+--- a/file1
++++ b/file1
+@@ -1 +1 @@
+-one
++uno
+`)
+	require.NoError(t, err)
+	require.Len(t, diff.Files, 4)
+
+	for i, expected := range []struct {
+		mode     FileMode
+		origName string
+		newName  string
+	}{
+		{Modified, "logo.png", "logo.png"},
+		{New, "", "new.png"},
+		{Modified, "icon.png", "icon.png"},
+	} {
+		file := diff.Files[i]
+		require.True(t, file.IsBinary, "file %d", i)
+		require.Equal(t, expected.mode, file.Mode, "file %d", i)
+		require.Equal(t, expected.origName, file.OrigName, "file %d", i)
+		require.Equal(t, expected.newName, file.NewName, "file %d", i)
+		require.Empty(t, file.Chunks, "file %d", i)
+	}
+	require.Empty(t, diff.Files[2].ExtraHeaders)
+
+	require.False(t, diff.Files[3].IsBinary)
+	require.Len(t, diff.Files[3].Chunks, 1)
+}
+
+func TestParseReader(t *testing.T) {
+	byt, err := ioutil.ReadFile("example.diff")
+	require.NoError(t, err)
+
+	expected := setup(t)
+	diff, err := ParseReader(iotest.OneByteReader(bytes.NewReader(byt)))
+	require.NoError(t, err)
+	require.Empty(t, diff.Raw)
+	expected.Raw = ""
+	require.Equal(t, expected, diff)
+}
+
+func TestParseReaderLongLines(t *testing.T) {
+	long := strings.Repeat("x", 1<<20)
+	diff, err := ParseReader(strings.NewReader("diff --git a/file1 b/file1\n--- a/file1\n+++ b/file1\n" +
+		"@@ -1 +1 @@\n-" + long + "\n+" + long + "y\n"))
+	require.NoError(t, err)
+	lines := diff.Files[0].Chunks[0].WholeRange.Lines
+	require.Len(t, lines, 2)
+	require.Equal(t, long, lines[0].Content)
+	require.Equal(t, long+"y", lines[1].Content)
+}
+
+func TestParseReaderError(t *testing.T) {
+	_, err := ParseReader(iotest.TimeoutReader(iotest.OneByteReader(strings.NewReader("diff --git a/f b/f\n"))))
+	require.Equal(t, iotest.ErrTimeout, err)
+}
+
+func TestLineContent(t *testing.T) {
+	diff, err := Parse(`diff --git a/file1 b/file1
+index 504d2a1..50ccec3 100644
+--- a/file1
++++ b/file1
+@@ -1,2 +1,2 @@
+ context
+-world
++hello
+`)
+	require.NoError(t, err)
+	chunk := diff.Files[0].Chunks[0]
+	require.Equal(t, "context", chunk.OrigRange.Lines[0].Content)
+	require.Equal(t, "context", chunk.NewRange.Lines[0].Content)
+	require.Equal(t, "world", chunk.OrigRange.Lines[1].Content)
+	require.Equal(t, "hello", chunk.NewRange.Lines[1].Content)
+}
+
+func TestEmptyLines(t *testing.T) {
+	diff, err := Parse("diff --git a/file1 b/file1\n" +
+		"index 504d2a1..50ccec3 100644\n" +
+		"--- a/file1\n" +
+		"+++ b/file1\n" +
+		"@@ -1,3 +1,3 @@\n" +
+		" one\n" +
+		"-\n" +
+		"+\n" +
+		" \n")
+	require.NoError(t, err)
+
+	lines := diff.Files[0].Chunks[0].WholeRange.Lines
+	require.Len(t, lines, 4)
+	for i, mode := range []DiffLineMode{Unchanged, Removed, Added, Unchanged} {
+		require.Equal(t, mode, lines[i].Mode)
+	}
+	require.Equal(t, "", lines[1].Content)
+	require.Equal(t, "", lines[2].Content)
+	require.Equal(t, "", lines[3].Content)
+}
+
+func BenchmarkParse(b *testing.B) {
+	diff := largeDiff(50, 20)
+	b.SetBytes(int64(len(diff)))
+	for i := 0; i < b.N; i++ {
+		_, err := Parse(diff)
+		require.NoError(b, err)
+	}
+}
@@ -0,0 +1,168 @@
+// Copyright (c) 2015 Jesse Meek <https://github.com/waigani>
+// This program is Free Software see LICENSE file for details.
+
+package diffparser
+
+import (
+	"path"
+	"strings"
+)
+
+// DefaultGeneratedPatterns are the patterns ExcludeGenerated uses when
+// called with nil.
+var DefaultGeneratedPatterns = []string{
+	"*.pb.go",
+	"*_generated.go",
+	"*.gen.go",
+	"*.min.js",
+	"*.min.css",
+	"go.sum",
+	"Gopkg.lock",
+	"Cargo.lock",
+	"Gemfile.lock",
+	"composer.lock",
+	"poetry.lock",
+	"package-lock.json",
+	"pnpm-lock.yaml",
+	"yarn.lock",
+}
+
+// ExcludeGenerated returns a diff holding the files of d that match none of
+// patterns, using DefaultGeneratedPatterns if patterns is nil. Patterns use
+// path.Match syntax; a pattern without a slash is matched against the base
+// name of the file, otherwise against the whole path. Malformed patterns
+// match nothing. The returned diff shares its files with d and has no Raw
+// text.
+func (d *Diff) ExcludeGenerated(patterns []string) *Diff {
+	if patterns == nil {
+		patterns = DefaultGeneratedPatterns
+	}
+
+	return d.filtered(func(f *DiffFile) bool {
+		return !matchAny(patterns, f.name())
+	})
+}
+
+// DiffFilter returns a diff holding the files of d selected by spec, a
+// filter in the style of "git diff --diff-filter". Upper case letters
+// select files of that kind and lower case letters exclude them; if spec
+// has no upper case letters every file not excluded is selected. The
+// letters are A (New), C (Copied), D (Deleted), M (Modified) and R
+// (Renamed); other git letters select nothing. The returned diff shares
+// its files with d and has no Raw text.
+func (d *Diff) DiffFilter(spec string) *Diff {
+	include := make(map[byte]bool)
+	exclude := make(map[byte]bool)
+	for i := 0; i < len(spec); i++ {
+		c := spec[i]
+		switch {
+		case c >= 'A' && c <= 'Z':
+			include[c] = true
+		case c >= 'a' && c <= 'z':
+			exclude[c-'a'+'A'] = true
+		}
+	}
+
+	return d.filtered(func(f *DiffFile) bool {
+		letter := f.Mode.filterLetter()
+		if exclude[letter] {
+			return false
+		}
+		return len(include) == 0 || include[letter]
+	})
+}
+
+// filterLetter returns the "git diff --diff-filter" letter of the mode.
+func (m FileMode) filterLetter() byte {
+	switch m {
+	case New:
+		return 'A'
+	case Deleted:
+		return 'D'
+	case Modified:
+		return 'M'
+	case Renamed:
+		return 'R'
+	case Copied:
+		return 'C'
+	}
+	return 'X'
+}
+
+// FilterFiles returns the files of the diff for which pred returns true, in
+// diff order.
+func (d *Diff) FilterFiles(pred func(*DiffFile) bool) []*DiffFile {
+	var files []*DiffFile
+	for _, f := range d.Files {
+		if pred(f) {
+			files = append(files, f)
+		}
+	}
+	return files
+}
+
+// MatchFiles returns the files of the diff whose name, the new name or for
+// deleted files the original one, matches pattern. The pattern is matched
+// as by path.Match against the whole name, except that a "**" element
+// matches any number of directories: "**/*.go" matches Go files at any
+// depth, including the top level. It returns an error if pattern is
+// malformed.
+func (d *Diff) MatchFiles(pattern string) ([]*DiffFile, error) {
+	// Check the whole pattern is well formed, even if the names don't
+	// reach some of its elements.
+	elems := strings.Split(pattern, "/")
+	for _, elem := range elems {
+		if _, err := path.Match(elem, ""); err != nil {
+			return nil, err
+		}
+	}
+	return d.FilterFiles(func(f *DiffFile) bool {
+		return matchGlob(elems, strings.Split(f.name(), "/"))
+	}), nil
+}
+
+// matchGlob reports whether the elements of a path match those of a
+// pattern, where a "**" pattern element matches zero or more elements.
+func matchGlob(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchGlob(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+// filtered returns a diff holding the files of d for which keep returns
+// true. The files are shared with d and the Raw text is dropped.
+func (d *Diff) filtered(keep func(*DiffFile) bool) *Diff {
+	c := *d
+	c.Raw = ""
+	c.Files = d.FilterFiles(keep)
+	return &c
+}
+
+// matchAny reports whether name matches any of patterns.
+func matchAny(patterns []string, name string) bool {
+	for _, p := range patterns {
+		target := name
+		if !strings.Contains(p, "/") {
+			target = path.Base(name)
+		}
+		if ok, _ := path.Match(p, target); ok {
+			return true
+		}
+	}
+	return false
+}
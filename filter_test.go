@@ -0,0 +1,86 @@
+// Copyright (c) 2015 Jesse Meek <https://github.com/waigani>
+// This program is Free Software see LICENSE file for details.
+
+package diffparser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func namedDiff(names ...string) *Diff {
+	var d Diff
+	for _, n := range names {
+		d.addFile(&DiffFile{Mode: Modified, OrigName: n, NewName: n})
+	}
+	return &d
+}
+
+func fileNames(files []*DiffFile) []string {
+	var names []string
+	for _, f := range files {
+		names = append(names, f.name())
+	}
+	return names
+}
+
+func TestExcludeGenerated(t *testing.T) {
+	diff := namedDiff("main.go", "api/api.pb.go", "go.sum", "web/yarn.lock", "gen/x_generated.go", "vendor/lib.go")
+
+	require.Equal(t, []string{"main.go", "vendor/lib.go"}, fileNames(diff.ExcludeGenerated(nil).Files))
+	require.Equal(t, []string{"main.go", "api/api.pb.go", "go.sum", "web/yarn.lock", "gen/x_generated.go"},
+		fileNames(diff.ExcludeGenerated([]string{"vendor/*"}).Files))
+	require.Len(t, diff.ExcludeGenerated([]string{"["}).Files, 6)
+	require.Len(t, diff.Files, 6)
+}
+
+func TestDiffFilter(t *testing.T) {
+	diff := setup(t)
+	for spec, expected := range map[string][]string{
+		"":   {"file1", "file2", "file3", "file4", "newname", "symlink"},
+		"A":  {"file4", "newname"},
+		"AM": {"file1", "file4", "newname"},
+		"d":  {"file1", "file4", "newname"},
+		"Da": {"file2", "file3", "symlink"},
+		"R":  nil,
+	} {
+		require.Equal(t, expected, fileNames(diff.DiffFilter(spec).Files), spec)
+	}
+}
+
+func TestFilterFiles(t *testing.T) {
+	diff := setup(t)
+	files := diff.FilterFiles(func(f *DiffFile) bool { return f.Mode == Deleted })
+	require.Equal(t, []string{"file2", "file3", "symlink"}, fileNames(files))
+	require.Len(t, diff.Files, 6)
+	require.Empty(t, diff.FilterFiles(func(*DiffFile) bool { return false }))
+}
+
+func TestMatchFiles(t *testing.T) {
+	diff := namedDiff("main.go", "cmd/tool/main.go", "src/app.js", "src/lib/util.go", "README.md")
+	for _, test := range []struct {
+		pattern string
+		names   []string
+	}{
+		{"**/*.go", []string{"main.go", "cmd/tool/main.go", "src/lib/util.go"}},
+		{"*.go", []string{"main.go"}},
+		{"src/*", []string{"src/app.js"}},
+		{"src/**", []string{"src/app.js", "src/lib/util.go"}},
+		{"cmd/**/main.go", []string{"cmd/tool/main.go"}},
+		{"**", []string{"main.go", "cmd/tool/main.go", "src/app.js", "src/lib/util.go", "README.md"}},
+		{"*.txt", nil},
+	} {
+		files, err := diff.MatchFiles(test.pattern)
+		require.NoError(t, err)
+		require.Equal(t, test.names, fileNames(files), test.pattern)
+	}
+
+	// Deleted files match by their original name.
+	files, err := setup(t).MatchFiles("file[23]")
+	require.NoError(t, err)
+	require.Equal(t, []string{"file2", "file3"}, fileNames(files))
+
+	_, err = diff.MatchFiles("src/[")
+	require.Error(t, err)
+}
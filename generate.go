@@ -0,0 +1,200 @@
+// Copyright (c) 2015 Jesse Meek <https://github.com/waigani>
+// This program is Free Software see LICENSE file for details.
+
+package diffparser
+
+import (
+	"strings"
+)
+
+// GenerateUnified returns the diff that turns orig into new, the contents
+// of the file fname, with three lines of context around each change, as
+// git diff does. See GenerateUnifiedWithContext.
+func GenerateUnified(orig, new, fname string) (*Diff, error) {
+	return GenerateUnifiedWithContext(orig, new, fname, 3)
+}
+
+// GenerateUnifiedWithContext returns the diff that turns orig into new, the
+// contents of the file fname, with at most context unchanged lines around
+// each change. The lines are compared with Myers' algorithm, which finds a
+// shortest edit. The diff is made as unified diff text, kept in Raw, and
+// parsed, so it is exactly what Parse returns for that text. Empty texts
+// are taken as empty files, not missing ones, and equal texts give a diff
+// without files.
+func GenerateUnifiedWithContext(orig, new, fname string, context int) (*Diff, error) {
+	a, b := splitLines(orig), splitLines(new)
+	hunk := &DiffChunk{
+		OrigRange: DiffRange{Start: 1, Length: len(a)},
+		NewRange:  DiffRange{Start: 1, Length: len(b)},
+	}
+	if len(a) == 0 {
+		hunk.OrigRange.Start = 0
+	}
+	if len(b) == 0 {
+		hunk.NewRange.Start = 0
+	}
+
+	line := func(mode DiffLineMode, s string) {
+		content := strings.TrimSuffix(s, "\n")
+		hunk.WholeRange.Lines = append(hunk.WholeRange.Lines, &DiffLine{
+			Mode:      mode,
+			Content:   content,
+			NoNewline: content == s,
+		})
+	}
+	i, j := 0, 0
+	for _, m := range matchLines(a, b) {
+		for ; i < m.a; i++ {
+			line(Removed, a[i])
+		}
+		for ; j < m.b; j++ {
+			line(Added, b[j])
+		}
+		line(Unchanged, b[j])
+		i++
+		j++
+	}
+	for ; i < len(a); i++ {
+		line(Removed, a[i])
+	}
+	for ; j < len(b); j++ {
+		line(Added, b[j])
+	}
+
+	if context < 0 {
+		context = 0
+	}
+	parts := hunk.trimContext(context)
+	if len(parts) == 0 {
+		return Parse("")
+	}
+	file := &DiffFile{Mode: Modified, OrigName: fname, NewName: fname, Chunks: []*DiffChunk{hunk}}
+	return Parse(file.patchHeader() + strings.Join(parts, ""))
+}
+
+// splitLines splits s into its lines, each with its "\n" terminator but
+// for a last line that has none.
+func splitLines(s string) []string {
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// lineMatch pairs equal lines of two texts by their indexes.
+type lineMatch struct {
+	a, b int
+}
+
+// matchLines returns the pairs of equal lines of a and b left by a
+// shortest edit from a to b, in order.
+func matchLines(a, b []string) []lineMatch {
+	// Lines are compared as numbers, one per distinct line.
+	ids := make(map[string]int)
+	number := func(lines []string) []int {
+		ns := make([]int, len(lines))
+		for i, l := range lines {
+			id, ok := ids[l]
+			if !ok {
+				id = len(ids)
+				ids[l] = id
+			}
+			ns[i] = id
+		}
+		return ns
+	}
+	var matches []lineMatch
+	matchRange(number(a), number(b), 0, 0, &matches)
+	return matches
+}
+
+// matchRange appends the matches of a shortest edit from a to b, which
+// start at aOff and bOff in the whole texts, to matches. It splits the
+// edit at its middle snake, which needs space linear in the texts' length.
+func matchRange(a, b []int, aOff, bOff int, matches *[]lineMatch) {
+	match := func(i, j, n int) {
+		for k := 0; k < n; k++ {
+			*matches = append(*matches, lineMatch{aOff + i + k, bOff + j + k})
+		}
+	}
+
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	match(0, 0, prefix)
+	a, b = a[prefix:], b[prefix:]
+	aOff, bOff = aOff+prefix, bOff+prefix
+
+	suffix := 0
+	for suffix < len(a) && suffix < len(b) && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	a, b = a[:len(a)-suffix], b[:len(b)-suffix]
+
+	// With no common prefix or suffix left, an edit between non-empty
+	// texts removes and adds at least a line each, so both halves around
+	// the middle snake are shorter edits.
+	if len(a) > 0 && len(b) > 0 {
+		x, y, u, v := middleSnake(a, b)
+		matchRange(a[:x], b[:y], aOff, bOff, matches)
+		match(x, y, u-x)
+		matchRange(a[u:], b[v:], aOff+u, bOff+v, matches)
+	}
+	match(len(a), len(b), suffix)
+}
+
+// middleSnake returns the start (x, y) and end (u, v) of the middle snake
+// of a shortest edit from a to b: the run of equal lines that a search
+// from both ends of the edit graph meets on.
+func middleSnake(a, b []int) (x, y, u, v int) {
+	n, m := len(a), len(b)
+	delta := n - m
+	odd := delta%2 != 0
+	max := (n + m + 1) / 2
+
+	// vf and vb hold the furthest x reached on each diagonal k, offset by
+	// max+1, searching forwards from the start and backwards from the end.
+	// The backward search runs on the reversed texts, where diagonal k is
+	// the forward search's diagonal delta-k.
+	off := max + 1
+	vf := make([]int, 2*max+3)
+	vb := make([]int, 2*max+3)
+	for d := 0; d <= max; d++ {
+		for k := -d; k <= d; k += 2 {
+			x := vf[off+k-1] + 1
+			if k == -d || k != d && vf[off+k-1] < vf[off+k+1] {
+				x = vf[off+k+1]
+			}
+			y := x - k
+			x0, y0 := x, y
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			vf[off+k] = x
+			if kb := delta - k; odd && kb >= -(d-1) && kb <= d-1 && x+vb[off+kb] >= n {
+				return x0, y0, x, y
+			}
+		}
+		for k := -d; k <= d; k += 2 {
+			x := vb[off+k-1] + 1
+			if k == -d || k != d && vb[off+k-1] < vb[off+k+1] {
+				x = vb[off+k+1]
+			}
+			y := x - k
+			x0, y0 := x, y
+			for x < n && y < m && a[n-1-x] == b[m-1-y] {
+				x++
+				y++
+			}
+			vb[off+k] = x
+			if kf := delta - k; !odd && kf >= -d && kf <= d && x+vf[off+kf] >= n {
+				return n - x, m - y, n - x0, m - y0
+			}
+		}
+	}
+	// A path always meets by d = max.
+	panic("diffparser: no middle snake")
+}
@@ -0,0 +1,120 @@
+// Copyright (c) 2015 Jesse Meek <https://github.com/waigani>
+// This program is Free Software see LICENSE file for details.
+
+package diffparser
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateUnified(t *testing.T) {
+	orig := "one\ntwo\nthree\nfour\nfive\nsix\nseven\neight\nnine\nten\neleven\ntwelve\n"
+	new := "uno\ntwo\nthree\nfour\nfive\nsix\nseven\neight\nnine\nten\neleven\ndoce"
+	diff, err := GenerateUnified(orig, new, "file1")
+	require.NoError(t, err)
+	require.Equal(t, `diff --git a/file1 b/file1
+--- a/file1
++++ b/file1
+@@ -1,4 +1,4 @@
+-one
++uno
+ two
+ three
+ four
+@@ -9,4 +9,4 @@
+ nine
+ ten
+ eleven
+-twelve
++doce
+\ No newline at end of file
+`, diff.Raw)
+
+	// The diff re-parses to the same structure.
+	reparsed, err := Parse(diff.String())
+	require.NoError(t, err)
+	require.Equal(t, diff.Files, reparsed.Files)
+
+	file := diff.Files[0]
+	require.Equal(t, "file1", file.OrigName)
+	require.Equal(t, "file1", file.NewName)
+	require.Len(t, file.Chunks, 2)
+	require.Equal(t, 9, file.Chunks[1].OrigRange.Start)
+	require.Equal(t, 4, file.Chunks[1].OrigRange.Length)
+
+	// More context joins the hunks.
+	diff, err = GenerateUnifiedWithContext(orig, new, "file1", 5)
+	require.NoError(t, err)
+	require.Len(t, diff.Files[0].Chunks, 1)
+
+	diff, err = GenerateUnifiedWithContext(orig, new, "file1", 0)
+	require.NoError(t, err)
+	require.Equal(t, 2, diff.Files[0].Chunks[0].LineCount())
+
+	diff, err = GenerateUnified(orig, orig, "file1")
+	require.NoError(t, err)
+	require.Empty(t, diff.Files)
+
+	diff, err = GenerateUnified("", "new\n", "file1")
+	require.NoError(t, err)
+	require.Equal(t, "@@ -0,0 +1,1 @@", diff.Files[0].Chunks[0].RawHeader)
+}
+
+func TestGenerateUnifiedApplies(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	text := func() string {
+		var lines []string
+		for i := r.Intn(30); i > 0; i-- {
+			lines = append(lines, string(rune('a'+r.Intn(4))))
+		}
+		s := strings.Join(lines, "\n")
+		if s != "" && r.Intn(4) > 0 {
+			s += "\n"
+		}
+		return s
+	}
+
+	for i := 0; i < 500; i++ {
+		orig, new := text(), text()
+		diff, err := GenerateUnifiedWithContext(orig, new, "file", r.Intn(4))
+		require.NoError(t, err)
+		if orig == new {
+			require.Empty(t, diff.Files)
+			continue
+		}
+		got, err := diff.Files[0].Apply(orig)
+		require.NoError(t, err)
+		require.Equal(t, new, got, "%q to %q", orig, new)
+
+		// The edit is a shortest one.
+		a, b := splitLines(orig), splitLines(new)
+		lcs := len(a) + len(b) - diff.Additions() - diff.Deletions()
+		require.Equal(t, 2*longestCommon(a, b), lcs, "%q to %q", orig, new)
+	}
+}
+
+// longestCommon returns the length of the longest common subsequence of a
+// and b.
+func longestCommon(a, b []string) int {
+	n := make([][]int, len(a)+1)
+	for i := range n {
+		n[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			switch {
+			case a[i] == b[j]:
+				n[i][j] = n[i+1][j+1] + 1
+			case n[i+1][j] > n[i][j+1]:
+				n[i][j] = n[i+1][j]
+			default:
+				n[i][j] = n[i][j+1]
+			}
+		}
+	}
+	return n[0][0]
+}
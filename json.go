@@ -0,0 +1,62 @@
+// Copyright (c) 2015 Jesse Meek <https://github.com/waigani>
+// This program is Free Software see LICENSE file for details.
+
+package diffparser
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// MarshalJSON implements json.Marshaler, encoding the mode as its lower
+// case name, such as "modified".
+func (m FileMode) MarshalJSON() ([]byte, error) {
+	name, ok := fileModeNames[m]
+	if !ok {
+		return nil, fmt.Errorf("cannot marshal unknown file mode %d", int(m))
+	}
+	return json.Marshal(strings.ToLower(name))
+}
+
+// UnmarshalJSON implements json.Unmarshaler, decoding a mode written by
+// MarshalJSON.
+func (m *FileMode) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	for mode, name := range fileModeNames {
+		if strings.ToLower(name) == s {
+			*m = mode
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown file mode %q", s)
+}
+
+// MarshalJSON implements json.Marshaler, encoding the mode as its lower
+// case name, such as "added".
+func (m DiffLineMode) MarshalJSON() ([]byte, error) {
+	name, ok := lineModeNames[m]
+	if !ok {
+		return nil, fmt.Errorf("cannot marshal unknown line mode %d", int(m))
+	}
+	return json.Marshal(strings.ToLower(name))
+}
+
+// UnmarshalJSON implements json.Unmarshaler, decoding a mode written by
+// MarshalJSON.
+func (m *DiffLineMode) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	for mode, name := range lineModeNames {
+		if strings.ToLower(name) == s {
+			*m = mode
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown line mode %q", s)
+}
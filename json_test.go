@@ -0,0 +1,47 @@
+// Copyright (c) 2015 Jesse Meek <https://github.com/waigani>
+// This program is Free Software see LICENSE file for details.
+
+package diffparser
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestModeJSON(t *testing.T) {
+	diff := setup(t)
+
+	data, err := json.Marshal(diff)
+	require.NoError(t, err)
+	require.Contains(t, string(data), `"Mode":"modified"`)
+	require.Contains(t, string(data), `"Mode":"added"`)
+
+	var got Diff
+	require.NoError(t, json.Unmarshal(data, &got))
+	for i, file := range diff.Files {
+		require.Equal(t, file.Mode, got.Files[i].Mode)
+		for j, chunk := range file.Chunks {
+			for k, line := range chunk.WholeRange.Lines {
+				require.Equal(t, line.Mode, got.Files[i].Chunks[j].WholeRange.Lines[k].Mode)
+			}
+		}
+	}
+
+	for _, mode := range []FileMode{Deleted, Modified, New, Renamed, Copied} {
+		data, err := json.Marshal(mode)
+		require.NoError(t, err)
+		var m FileMode
+		require.NoError(t, json.Unmarshal(data, &m))
+		require.Equal(t, mode, m)
+	}
+
+	var m FileMode
+	require.EqualError(t, json.Unmarshal([]byte(`"moved"`), &m), `unknown file mode "moved"`)
+	var lm DiffLineMode
+	require.EqualError(t, json.Unmarshal([]byte(`"changed"`), &lm), `unknown line mode "changed"`)
+	require.Error(t, json.Unmarshal([]byte(`1`), &lm))
+	_, err = json.Marshal(FileMode(9))
+	require.Error(t, err)
+}
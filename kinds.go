@@ -0,0 +1,79 @@
+// Copyright (c) 2015 Jesse Meek <https://github.com/waigani>
+// This program is Free Software see LICENSE file for details.
+
+package diffparser
+
+import (
+	"path"
+	"strings"
+)
+
+// commentPrefixes maps a lowercased file extension to the prefixes that
+// start a comment line in that language.
+var commentPrefixes = map[string][]string{
+	".c":     {"//", "/*"},
+	".cc":    {"//", "/*"},
+	".cpp":   {"//", "/*"},
+	".cs":    {"//", "/*"},
+	".go":    {"//", "/*"},
+	".h":     {"//", "/*"},
+	".java":  {"//", "/*"},
+	".js":    {"//", "/*"},
+	".kt":    {"//", "/*"},
+	".php":   {"//", "/*", "#"},
+	".rs":    {"//", "/*"},
+	".scala": {"//", "/*"},
+	".swift": {"//", "/*"},
+	".ts":    {"//", "/*"},
+	".py":    {"#"},
+	".rb":    {"#"},
+	".sh":    {"#"},
+	".pl":    {"#"},
+	".r":     {"#"},
+	".yaml":  {"#"},
+	".yml":   {"#"},
+	".toml":  {"#"},
+	".lua":   {"--"},
+	".sql":   {"--"},
+	".hs":    {"--"},
+}
+
+// name returns the file's new name, or its original name if it has no new
+// name (e.g. it was deleted).
+func (f *DiffFile) name() string {
+	if f.NewName != "" {
+		return f.NewName
+	}
+	return f.OrigName
+}
+
+// ChangeKinds classifies the file's added and removed lines as code,
+// comment or blank. A line is a comment if, ignoring leading whitespace, it
+// starts with a comment prefix of the language guessed from the file
+// extension. This is a heuristic: block comment bodies and trailing
+// comments count as code, and files of unknown languages have no comments.
+func (f *DiffFile) ChangeKinds() (codeChanged, commentChanged, blankChanged int) {
+	prefixes := commentPrefixes[strings.ToLower(path.Ext(f.name()))]
+
+	for _, h := range f.Chunks {
+	lines:
+		for _, l := range h.WholeRange.Lines {
+			if l.Mode == Unchanged {
+				continue
+			}
+			content := l.Trimmed()
+			if content == "" {
+				blankChanged++
+				continue
+			}
+			for _, p := range prefixes {
+				if strings.HasPrefix(content, p) {
+					commentChanged++
+					continue lines
+				}
+			}
+			codeChanged++
+		}
+	}
+	return codeChanged, commentChanged, blankChanged
+}
@@ -0,0 +1,58 @@
+// Copyright (c) 2015 Jesse Meek <https://github.com/waigani>
+// This program is Free Software see LICENSE file for details.
+
+package diffparser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestChangeKinds(t *testing.T) {
+	diff, err := Parse(`diff --git a/main.go b/main.go
+index 504d2a1..50ccec3 100644
+--- a/main.go
++++ b/main.go
+@@ -1,4 +1,6 @@
+ package main
+-// old comment
++// Main runs the program.
++
+ func main() {
+-	run()
++	// run it
++	run(ctx)
+ }
+diff --git a/main.py b/main.py
+index 504d2a1..50ccec3 100644
+--- a/main.py
++++ b/main.py
+@@ -1,2 +1,3 @@
+-# old
++# new
++x = 1  # trailing
+ 
+diff --git a/README b/README
+index 504d2a1..50ccec3 100644
+--- a/README
++++ b/README
+@@ -1 +1 @@
+-# title
++# Title
+`)
+	require.NoError(t, err)
+
+	for i, expected := range []struct {
+		code, comment, blank int
+	}{
+		{code: 2, comment: 3, blank: 1},
+		{code: 1, comment: 2, blank: 0},
+		{code: 2, comment: 0, blank: 0},
+	} {
+		code, comment, blank := diff.Files[i].ChangeKinds()
+		require.Equal(t, expected.code, code, "file %d", i)
+		require.Equal(t, expected.comment, comment, "file %d", i)
+		require.Equal(t, expected.blank, blank, "file %d", i)
+	}
+}
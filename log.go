@@ -0,0 +1,176 @@
+// Copyright (c) 2015 Jesse Meek <https://github.com/waigani>
+// This program is Free Software see LICENSE file for details.
+
+package diffparser
+
+import (
+	"errors"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Commit is a commit as printed by "git log -p": its metadata, message and
+// diff.
+type Commit struct {
+	SHA     string
+	Parents []string // the abbreviated parents of a merge, from "Merge:"
+	Author  string
+	Date    string // as git log prints it, which depends on --date
+	Message string
+
+	*Diff
+}
+
+// reLogCommit matches the "commit <sha>" line that starts each commit of
+// "git log" output, which may be followed by decorations such as
+// "(HEAD -> main)".
+var reLogCommit = regexp.MustCompile(`^commit ([0-9a-f]{40})(?:\s|$)`)
+
+// LogOptions selects the commits and files ParseLogWithOptions keeps.
+type LogOptions struct {
+	// AuthorFilter, if set, keeps only the commits whose "Author:" line,
+	// "Name <email>", contains it.
+	AuthorFilter string
+
+	// PathFilter, if set, keeps only the files whose original or new name
+	// matches it, as a MatchFiles pattern, and the commits left with any
+	// such file.
+	PathFilter string
+}
+
+// ParseLog parses the output of "git log -p" into its commits, in log
+// order. Each commit starts at a "commit <sha>" line. Diff lines can't be
+// mistaken for one, as they start with " ", "+" or "-", and message lines
+// are indented.
+func ParseLog(s string) ([]*Commit, error) {
+	return ParseLogWithOptions(s, LogOptions{})
+}
+
+// ParseLogWithOptions parses a log as ParseLog does, keeping only the
+// commits and files opts selects. The others are skipped before they are
+// parsed, so a large history can be searched without keeping all of it.
+// It returns an error if opts.PathFilter is malformed.
+func ParseLogWithOptions(s string, opts LogOptions) ([]*Commit, error) {
+	var pathFilter []string
+	if opts.PathFilter != "" {
+		pathFilter = strings.Split(opts.PathFilter, "/")
+		for _, elem := range pathFilter {
+			if _, err := path.Match(elem, ""); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	var commits []*Commit
+	var section []string
+	end := func() error {
+		if section == nil {
+			return nil
+		}
+		c, err := parseLogCommit(section, opts.AuthorFilter, pathFilter)
+		if err != nil {
+			return err
+		}
+		if c != nil {
+			commits = append(commits, c)
+		}
+		return nil
+	}
+
+	for _, l := range strings.SplitAfter(s, "\n") {
+		if reLogCommit.MatchString(l) {
+			if err := end(); err != nil {
+				return nil, err
+			}
+			section = []string{}
+		}
+		if section == nil {
+			if strings.TrimSpace(l) != "" {
+				return nil, errors.New("log does not start with a \"commit <sha>\" line")
+			}
+			continue
+		}
+		section = append(section, l)
+	}
+	if err := end(); err != nil {
+		return nil, err
+	}
+	return commits, nil
+}
+
+// parseLogCommit parses the lines of one commit of "git log -p" output. It
+// returns nil if the commit's author does not contain author, or, for a
+// non-nil pathFilter, none of its files match it; the files that don't
+// match are left out.
+func parseLogCommit(lines []string, author string, pathFilter []string) (*Commit, error) {
+	c := &Commit{SHA: reLogCommit.FindStringSubmatch(lines[0])[1]}
+
+	i := 1
+	for ; i < len(lines); i++ {
+		l := strings.TrimRight(lines[i], "\r\n")
+		if l == "" {
+			break
+		}
+		switch {
+		case strings.HasPrefix(l, "Merge:"):
+			c.Parents = strings.Fields(strings.TrimPrefix(l, "Merge:"))
+		case strings.HasPrefix(l, "Author:"):
+			c.Author = strings.TrimSpace(strings.TrimPrefix(l, "Author:"))
+		case strings.HasPrefix(l, "Date:"):
+			c.Date = strings.TrimSpace(strings.TrimPrefix(l, "Date:"))
+		}
+	}
+	if !strings.Contains(c.Author, author) {
+		return nil, nil
+	}
+
+	// The message is indented by four spaces, and ends at the first line
+	// that is not, other than blank lines.
+	var message []string
+	for i++; i < len(lines); i++ {
+		l := strings.TrimRight(lines[i], "\r\n")
+		if l != "" && !strings.HasPrefix(l, "    ") {
+			break
+		}
+		message = append(message, strings.TrimPrefix(l, "    "))
+	}
+	c.Message = strings.TrimSpace(strings.Join(message, "\n"))
+
+	patch := lines[i:]
+	if pathFilter != nil {
+		if patch = filterLogFiles(patch, pathFilter); patch == nil {
+			return nil, nil
+		}
+	}
+
+	diff, err := Parse(strings.Join(patch, ""))
+	if err != nil {
+		return nil, err
+	}
+	c.Diff = diff
+	return c, nil
+}
+
+// filterLogFiles returns the lines of a commit's diff without the files,
+// each starting at a "diff " line, whose names don't match pathFilter. It
+// returns nil if no file matches.
+func filterLogFiles(lines []string, pathFilter []string) []string {
+	var kept []string
+	keep, matched := true, false
+	for _, l := range lines {
+		if strings.HasPrefix(l, "diff ") {
+			origName, newName := gitHeaderNames(strings.TrimRight(l, "\r\n"), "a/", "b/")
+			keep = origName != "" && matchGlob(pathFilter, strings.Split(origName, "/")) ||
+				newName != "" && matchGlob(pathFilter, strings.Split(newName, "/"))
+			matched = matched || keep
+		}
+		if keep {
+			kept = append(kept, l)
+		}
+	}
+	if !matched {
+		return nil
+	}
+	return kept
+}
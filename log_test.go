@@ -0,0 +1,116 @@
+// Copyright (c) 2015 Jesse Meek <https://github.com/waigani>
+// This program is Free Software see LICENSE file for details.
+
+package diffparser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const gitLog = `commit 75c3e9efe330ede7bfa7cdcafa0a94573f43be48 (HEAD -> main)
+Merge: 5ce5424 7693c33
+Author: Jane Doe <jane@example.com>
+Date:   Wed Oct 14 07:57:50 2026 +0000
+
+    Merge branch 'side'
+
+commit 5ce542458522ec5659e07ad07b0c1700a836b213
+Author: Jane Doe <jane@example.com>
+Date:   Wed Oct 14 07:50:00 2026 +0000
+
+    Change file1
+
+    The body has
+    two paragraphs.
+
+diff --git a/file1 b/file1
+index 4cb29ea..02dc020 100644
+--- a/file1
++++ b/file1
+@@ -1,3 +1,3 @@
+ commit 1111111111111111111111111111111111111111
+-commit 2222222222222222222222222222222222222222
++commit 3333333333333333333333333333333333333333
+ three
+diff --git a/file2 b/file2
+index 4cb29ea..02dc020 100644
+--- a/file2
++++ b/file2
+@@ -1 +1 @@
+-a
++b
+`
+
+func TestParseLog(t *testing.T) {
+	commits, err := ParseLog(gitLog)
+	require.NoError(t, err)
+	require.Len(t, commits, 2)
+
+	merge := commits[0]
+	require.Equal(t, "75c3e9efe330ede7bfa7cdcafa0a94573f43be48", merge.SHA)
+	require.Equal(t, []string{"5ce5424", "7693c33"}, merge.Parents)
+	require.Equal(t, "Jane Doe <jane@example.com>", merge.Author)
+	require.Equal(t, "Wed Oct 14 07:57:50 2026 +0000", merge.Date)
+	require.Equal(t, "Merge branch 'side'", merge.Message)
+	require.Empty(t, merge.Files)
+
+	c := commits[1]
+	require.Equal(t, "5ce542458522ec5659e07ad07b0c1700a836b213", c.SHA)
+	require.Nil(t, c.Parents)
+	require.Equal(t, "Change file1\n\nThe body has\ntwo paragraphs.", c.Message)
+	require.Len(t, c.Files, 2)
+	lines := c.Files[0].Chunks[0].WholeRange.Lines
+	require.Len(t, lines, 4)
+	require.Equal(t, "commit 3333333333333333333333333333333333333333", lines[2].Content)
+
+	_, err = ParseLog("diff --git a/file1 b/file1\n")
+	require.EqualError(t, err, `log does not start with a "commit <sha>" line`)
+
+	commits, err = ParseLog("")
+	require.NoError(t, err)
+	require.Empty(t, commits)
+}
+
+func TestParseLogWithOptions(t *testing.T) {
+	log := gitLog + `commit 7693c33a1b2c3d4e5f60718293a4b5c6d7e8f901
+Author: John Roe <john@example.com>
+Date:   Tue Oct 13 07:50:00 2026 +0000
+
+    Change docs
+
+diff --git a/docs/guide.md b/docs/guide.md
+index 4cb29ea..02dc020 100644
+--- a/docs/guide.md
++++ b/docs/guide.md
+@@ -1 +1 @@
+-a
++b
+`
+	commits, err := ParseLogWithOptions(log, LogOptions{AuthorFilter: "john@example.com"})
+	require.NoError(t, err)
+	require.Len(t, commits, 1)
+	require.Equal(t, "John Roe <john@example.com>", commits[0].Author)
+	require.Len(t, commits[0].Files, 1)
+
+	commits, err = ParseLogWithOptions(log, LogOptions{PathFilter: "file2"})
+	require.NoError(t, err)
+	require.Len(t, commits, 1)
+	require.Equal(t, "5ce542458522ec5659e07ad07b0c1700a836b213", commits[0].SHA)
+	require.Len(t, commits[0].Files, 1)
+	require.Equal(t, "file2", commits[0].Files[0].NewName)
+	require.NotContains(t, commits[0].Raw, "file1")
+
+	commits, err = ParseLogWithOptions(log, LogOptions{PathFilter: "**/*.md"})
+	require.NoError(t, err)
+	require.Len(t, commits, 1)
+	require.Equal(t, "docs/guide.md", commits[0].Files[0].NewName)
+
+	commits, err = ParseLogWithOptions(log, LogOptions{AuthorFilter: "Jane", PathFilter: "**/*.md"})
+	require.NoError(t, err)
+	require.Empty(t, commits)
+
+	_, err = ParseLogWithOptions(log, LogOptions{PathFilter: "["})
+	require.Error(t, err)
+}
@@ -0,0 +1,95 @@
+// Copyright (c) 2015 Jesse Meek <https://github.com/waigani>
+// This program is Free Software see LICENSE file for details.
+
+package diffparser
+
+// Move is a block of lines removed from one file and added verbatim to
+// another.
+type Move struct {
+	From      *DiffFile
+	FromLines []*DiffLine
+	To        *DiffFile
+	ToLines   []*DiffLine
+}
+
+// DetectMoves returns the contiguous removed blocks of at least minLines
+// lines whose content was added, as a contiguous block with the same
+// lines, to a different file. Values of minLines below 1 are treated as 1.
+// Each added block is matched at most once.
+func (d *Diff) DetectMoves(minLines int) []Move {
+	if minLines < 1 {
+		minLines = 1
+	}
+
+	type block struct {
+		file  *DiffFile
+		lines []*DiffLine
+	}
+	var removed, added []block
+	for _, f := range d.Files {
+		for _, h := range f.Chunks {
+			for _, lines := range h.blocks(Removed) {
+				if len(lines) >= minLines {
+					removed = append(removed, block{f, lines})
+				}
+			}
+			for _, lines := range h.blocks(Added) {
+				if len(lines) >= minLines {
+					added = append(added, block{f, lines})
+				}
+			}
+		}
+	}
+
+	var moves []Move
+	used := make([]bool, len(added))
+	for _, r := range removed {
+		for i, a := range added {
+			if used[i] || a.file == r.file || !sameContent(r.lines, a.lines) {
+				continue
+			}
+			used[i] = true
+			moves = append(moves, Move{
+				From:      r.file,
+				FromLines: r.lines,
+				To:        a.file,
+				ToLines:   a.lines,
+			})
+			break
+		}
+	}
+	return moves
+}
+
+// blocks returns the runs of consecutive lines with the given mode in the
+// hunk.
+func (hunk *DiffChunk) blocks(mode DiffLineMode) [][]*DiffLine {
+	var blocks [][]*DiffLine
+	var cur []*DiffLine
+	for _, l := range hunk.WholeRange.Lines {
+		if l.Mode == mode {
+			cur = append(cur, l)
+			continue
+		}
+		if cur != nil {
+			blocks = append(blocks, cur)
+			cur = nil
+		}
+	}
+	if cur != nil {
+		blocks = append(blocks, cur)
+	}
+	return blocks
+}
+
+func sameContent(a, b []*DiffLine) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Content != b[i].Content {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,51 @@
+// Copyright (c) 2015 Jesse Meek <https://github.com/waigani>
+// This program is Free Software see LICENSE file for details.
+
+package diffparser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const moveDiff = `diff --git a/a.go b/a.go
+index 504d2a1..50ccec3 100644
+--- a/a.go
++++ b/a.go
+@@ -1,5 +1,2 @@
+ package a
+-func helper() {
+-	return
+-}
+ func A() {}
+diff --git a/b.go b/b.go
+index 504d2a1..50ccec3 100644
+--- a/b.go
++++ b/b.go
+@@ -1,2 +1,6 @@
+ package b
++x := 1
+ func B() {}
++func helper() {
++	return
++}
+`
+
+func TestDetectMoves(t *testing.T) {
+	diff, err := Parse(moveDiff)
+	require.NoError(t, err)
+
+	moves := diff.DetectMoves(2)
+	require.Len(t, moves, 1)
+	move := moves[0]
+	require.Equal(t, "a.go", move.From.NewName)
+	require.Equal(t, "b.go", move.To.NewName)
+	require.Len(t, move.FromLines, 3)
+	require.Equal(t, 2, move.FromLines[0].Number)
+	require.Len(t, move.ToLines, 3)
+	require.Equal(t, 4, move.ToLines[0].Number)
+	require.Equal(t, "func helper() {", move.ToLines[0].Content)
+
+	require.Empty(t, diff.DetectMoves(4))
+}
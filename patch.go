@@ -0,0 +1,320 @@
+// Copyright (c) 2015 Jesse Meek <https://github.com/waigani>
+// This program is Free Software see LICENSE file for details.
+
+package diffparser
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// HunkPatches returns one patch per hunk of the file. Each patch carries
+// the file headers followed by a single hunk, so it can be applied on its
+// own against the original file, as "git add -p" does.
+func (f *DiffFile) HunkPatches() []string {
+	header := f.patchHeader()
+	patches := make([]string, 0, len(f.Chunks))
+	for _, h := range f.Chunks {
+		patches = append(patches, header+h.String())
+	}
+	return patches
+}
+
+// String returns the diff as unified diff text: any text before the first
+// file followed by each file's header and hunks.
+func (d *Diff) String() string {
+	var b strings.Builder
+	b.WriteString(d.UnparsedPrefix)
+	for _, f := range d.Files {
+		b.WriteString(f.String())
+	}
+	return b.String()
+}
+
+// FormatOptions controls how Format writes a diff.
+type FormatOptions struct {
+	// LineEnding ends each line of the diff, "\n" if empty. Use "\r\n"
+	// for patches read on Windows.
+	LineEnding string
+}
+
+// Format returns the diff as String does, with the lines of its files
+// ended by opts.LineEnding. UnparsedPrefix is written as it is. A line
+// without a newline in its file is still followed by its "\ No newline at
+// end of file" marker, which is ended the same way.
+func (d *Diff) Format(opts FormatOptions) string {
+	eol := opts.LineEnding
+	if eol == "" {
+		eol = "\n"
+	}
+	var b strings.Builder
+	b.WriteString(d.UnparsedPrefix)
+	for _, f := range d.Files {
+		// Names are quoted and contents split at newlines, so every
+		// newline of the text ends a line.
+		b.WriteString(strings.Replace(f.String(), "\n", eol, -1))
+	}
+	return b.String()
+}
+
+// String returns the file's header followed by its hunks.
+func (f *DiffFile) String() string {
+	var b strings.Builder
+	b.WriteString(f.patchHeader())
+	for _, h := range f.Chunks {
+		b.WriteString(h.String())
+	}
+	return b.String()
+}
+
+// patchHeader returns the header lines of the file, from "diff --git" to
+// "+++". The "---" and "+++" lines are only included if the file has hunks,
+// as git does.
+func (f *DiffFile) patchHeader() string {
+	origName, newName := f.OrigName, f.NewName
+	switch f.Mode {
+	case New:
+		origName = newName
+	case Deleted:
+		newName = origName
+	}
+	orig, new := quoteName("a/"+origName), quoteName("b/"+newName)
+
+	var b strings.Builder
+	b.WriteString("diff --git " + orig + " " + new + "\n")
+	switch f.Mode {
+	case New:
+		orig = "/dev/null"
+	case Deleted:
+		new = "/dev/null"
+	}
+	if f.OldMode != "" {
+		b.WriteString("old mode " + f.OldMode + "\n")
+	}
+	if f.NewMode != "" {
+		b.WriteString("new mode " + f.NewMode + "\n")
+	}
+	for _, h := range f.ExtraHeaders {
+		b.WriteString(h + "\n")
+	}
+	switch f.Mode {
+	case Renamed:
+		b.WriteString("rename from " + quoteName(f.OrigName) + "\n")
+		b.WriteString("rename to " + quoteName(f.NewName) + "\n")
+	case Copied:
+		b.WriteString("copy from " + quoteName(f.OrigName) + "\n")
+		b.WriteString("copy to " + quoteName(f.NewName) + "\n")
+	}
+	if f.OrigSHA != "" || f.NewSHA != "" {
+		b.WriteString("index " + f.OrigSHA + ".." + f.NewSHA)
+		if f.IndexMode != "" {
+			b.WriteString(" " + f.IndexMode)
+		}
+		b.WriteString("\n")
+	}
+	switch {
+	case f.BinaryPatch != nil:
+		b.WriteString("GIT binary patch\n")
+		for _, l := range f.BinaryPatch {
+			b.WriteString(l + "\n")
+		}
+	case f.IsBinary && len(f.Chunks) == 0:
+		b.WriteString("Binary files " + orig + " and " + new + " differ\n")
+	}
+	if len(f.Chunks) > 0 {
+		b.WriteString("--- " + orig + "\n")
+		b.WriteString("+++ " + new + "\n")
+	}
+	return b.String()
+}
+
+// quoteName quotes a path as git does when it holds control characters,
+// '"', '\\' or non-ASCII bytes, with C-style escapes. Other paths are
+// returned as they are. unquoteName reverses it.
+func quoteName(s string) string {
+	needed := false
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c < 0x20 || c >= 0x7f || c == '"' || c == '\\' {
+			needed = true
+			break
+		}
+	}
+	if !needed {
+		return s
+	}
+
+	var b strings.Builder
+	b.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '\a':
+			b.WriteString(`\a`)
+		case '\b':
+			b.WriteString(`\b`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\v':
+			b.WriteString(`\v`)
+		case '\f':
+			b.WriteString(`\f`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '"', '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		default:
+			if c < 0x20 || c >= 0x7f {
+				b.WriteString(fmt.Sprintf(`\%03o`, c))
+			} else {
+				b.WriteByte(c)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// String returns the hunk's "@@" header line followed by its lines. The
+// header is RawHeader if it is set, or else made from the ranges.
+func (hunk *DiffChunk) String() string {
+	if hunk.RawHeader != "" {
+		return hunk.RawHeader + "\n" + formatLines(hunk.WholeRange.Lines)
+	}
+	return formatHunk(hunk.OrigRange, hunk.NewRange, hunk.ChunkHeader, hunk.WholeRange.Lines)
+}
+
+// formatHunk returns a "@@" header line for the given ranges and section
+// heading, followed by lines.
+func formatHunk(orig, new DiffRange, heading string, lines []*DiffLine) string {
+	var b strings.Builder
+	b.WriteString("@@ -" + formatRange(orig) + " +" + formatRange(new) + " @@")
+	if heading != "" {
+		b.WriteString(" " + heading)
+	}
+	b.WriteString("\n")
+	b.WriteString(formatLines(lines))
+	return b.String()
+}
+
+// formatLines returns lines as they appear in a hunk.
+func formatLines(lines []*DiffLine) string {
+	var b strings.Builder
+	for _, l := range lines {
+		b.WriteString(l.Mode.prefix() + l.text() + "\n")
+		if l.NoNewline {
+			b.WriteString(noNewlineMarker + "\n")
+		}
+	}
+	return b.String()
+}
+
+// ToUnified returns the diff as unified diff text with at most context
+// unchanged lines around each change. Context can only be reduced: hunks
+// never gain lines beyond those present in the parsed diff. Trimming may
+// isolate changes, in which case the hunk is split and each part keeps the
+// original section heading. Files without hunks are omitted.
+func (d *Diff) ToUnified(context int) string {
+	if context < 0 {
+		context = 0
+	}
+
+	var b strings.Builder
+	for _, f := range d.Files {
+		if len(f.Chunks) == 0 {
+			continue
+		}
+		b.WriteString(f.patchHeader())
+		for _, h := range f.Chunks {
+			for _, part := range h.trimContext(context) {
+				b.WriteString(part)
+			}
+		}
+	}
+	return b.String()
+}
+
+// trimContext returns the hunk re-emitted as one or more hunks with at most
+// context unchanged lines around each run of changes.
+func (hunk *DiffChunk) trimContext(context int) []string {
+	lines := hunk.WholeRange.Lines
+
+	// Line numbers on each side before each line of the hunk.
+	origNums := make([]int, len(lines)+1)
+	newNums := make([]int, len(lines)+1)
+	origNums[0], newNums[0] = hunk.OrigRange.Start, hunk.NewRange.Start
+	if hunk.OrigRange.Length == 0 {
+		origNums[0]++
+	}
+	if hunk.NewRange.Length == 0 {
+		newNums[0]++
+	}
+	for i, l := range lines {
+		origNums[i+1], newNums[i+1] = origNums[i], newNums[i]
+		if l.Mode != Added {
+			origNums[i+1]++
+		}
+		if l.Mode != Removed {
+			newNums[i+1]++
+		}
+	}
+
+	// Group the changed lines, joining groups whose surrounding context
+	// would touch or overlap.
+	var parts []string
+	start, end := -1, -1
+	flush := func() {
+		s := start - context
+		if s < 0 {
+			s = 0
+		}
+		e := end + context
+		if e > len(lines) {
+			e = len(lines)
+		}
+		orig := DiffRange{Start: origNums[s], Length: origNums[e] - origNums[s]}
+		new := DiffRange{Start: newNums[s], Length: newNums[e] - newNums[s]}
+		if orig.Length == 0 {
+			orig.Start--
+		}
+		if new.Length == 0 {
+			new.Start--
+		}
+		parts = append(parts, formatHunk(orig, new, hunk.ChunkHeader, lines[s:e]))
+	}
+	for i, l := range lines {
+		if l.Mode == Unchanged {
+			continue
+		}
+		if start >= 0 && i-end > 2*context {
+			flush()
+			start = -1
+		}
+		if start < 0 {
+			start = i
+		}
+		end = i + 1
+	}
+	if start >= 0 {
+		flush()
+	}
+	return parts
+}
+
+// formatRange formats a range as it appears in a hunk header.
+func formatRange(r DiffRange) string {
+	return strconv.Itoa(r.Start) + "," + strconv.Itoa(r.Length)
+}
+
+// prefix returns the character marking a line of this mode in a diff.
+func (m DiffLineMode) prefix() string {
+	switch m {
+	case Added:
+		return "+"
+	case Removed:
+		return "-"
+	}
+	return " "
+}
@@ -0,0 +1,299 @@
+// Copyright (c) 2015 Jesse Meek <https://github.com/waigani>
+// This program is Free Software see LICENSE file for details.
+
+package diffparser
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const multiHunkDiff = `diff --git a/file1 b/file1
+index 504d2a1..50ccec3 100644
+--- a/file1
++++ b/file1
+@@ -1,4 +1,4 @@ first
+-one
++uno
+ two
+ three
+ four
+@@ -7,4 +7,4 @@ second
+ seven
+ eight
+-nine
++nueve
+ ten
+`
+
+const multiHunkOrig = "one\ntwo\nthree\nfour\nfive\nsix\nseven\neight\nnine\nten\n"
+
+// gitApply applies patch to a file named name holding content in a fresh
+// directory, and returns the resulting content.
+func gitApply(t *testing.T, name, content, patch string) string {
+	return gitApplyFiles(t, map[string]string{name: content}, patch)[name]
+}
+
+// gitApplyFiles applies patch to files, a map of path to content, in a
+// fresh directory, and returns the files the directory then holds.
+func gitApplyFiles(t *testing.T, files map[string]string, patch string) map[string]string {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir, err := ioutil.TempDir("", "diffparser")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, ioutil.WriteFile(path, []byte(content), 0644))
+	}
+
+	cmd := exec.Command("git", "apply", "-")
+	cmd.Dir = dir
+	cmd.Stdin = strings.NewReader(patch)
+	out, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(out))
+
+	result := make(map[string]string)
+	require.NoError(t, filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		byt, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		name, err := filepath.Rel(dir, path)
+		result[filepath.ToSlash(name)] = string(byt)
+		return err
+	}))
+	return result
+}
+
+func TestBinaryPatchRoundTrip(t *testing.T) {
+	input := `diff --git a/icon.bin b/icon.bin
+index 3718208c68e4301ac18209bde0a13e3129cdd8f9..bf717495a81d1debcace33ca7b0b5f2ce2682ad0 100644
+GIT binary patch
+literal 15
+WcmZQzWX?#<$;nqJ&o9bJ` + "`" + `3C?Wo&{e3
+
+literal 9
+QcmZQzWXed*$;tl@00~(G7XSbN
+
+`
+	orig, new := "\x00\x01\x02hello\xff", "\x00\x01\x03hello world\xfe"
+
+	diff, err := Parse(input)
+	require.NoError(t, err)
+	f := diff.Files[0]
+	require.True(t, f.IsBinary)
+	require.Equal(t, []string{
+		"literal 15", "WcmZQzWX?#<$;nqJ&o9bJ`3C?Wo&{e3", "",
+		"literal 9", "QcmZQzWXed*$;tl@00~(G7XSbN", "",
+	}, f.BinaryPatch)
+	require.Equal(t, input, diff.String())
+	require.Equal(t, new, gitApply(t, "icon.bin", orig, diff.String()))
+	require.Equal(t, orig, gitApply(t, "icon.bin", new, diff.Reverse().String()))
+}
+
+func TestQuotedNamesRoundTrip(t *testing.T) {
+	input := `diff --git "a/ta\tb.txt" "b/ta\tb.txt"
+index 504d2a1..50ccec3 100644
+--- "a/ta\tb.txt"
++++ "b/ta\tb.txt"
+@@ -1 +1 @@
+-one
++uno
+`
+	diff, err := Parse(input)
+	require.NoError(t, err)
+	require.Equal(t, "ta\tb.txt", diff.Files[0].NewName)
+	require.Equal(t, input, diff.String())
+	require.Equal(t, "uno\n", gitApply(t, "ta\tb.txt", "one\n", diff.String()))
+
+	diff.Files[0].OrigName = `q"uo\te`
+	diff.Files[0].NewName = "\u00e9.txt"
+	diff.Files[0].Mode = Renamed
+	header := diff.Files[0].patchHeader()
+	require.Equal(t, `diff --git "a/q\"uo\\te" "b/\303\251.txt"
+rename from "q\"uo\\te"
+rename to "\303\251.txt"
+`, header[:strings.Index(header, "index")])
+	reparsed, err := Parse(diff.String())
+	require.NoError(t, err)
+	require.Equal(t, `q"uo\te`, reparsed.Files[0].OrigName)
+	require.Equal(t, "\u00e9.txt", reparsed.Files[0].NewName)
+
+	generated, err := GenerateUnified("one\n", "uno\n", "ta\tb.txt")
+	require.NoError(t, err)
+	require.Equal(t, "ta\tb.txt", generated.Files[0].OrigName)
+	require.Equal(t, "ta\tb.txt", generated.Files[0].NewName)
+	require.Equal(t, "uno\n", gitApply(t, "ta\tb.txt", "one\n", generated.Raw))
+}
+
+func TestHunkPatches(t *testing.T) {
+	diff, err := Parse(multiHunkDiff)
+	require.NoError(t, err)
+
+	patches := diff.Files[0].HunkPatches()
+	require.Len(t, patches, 2)
+	require.Equal(t, `diff --git a/file1 b/file1
+index 504d2a1..50ccec3 100644
+--- a/file1
++++ b/file1
+@@ -1,4 +1,4 @@ first
+-one
++uno
+ two
+ three
+ four
+`, patches[0])
+
+	require.Equal(t, strings.Replace(multiHunkOrig, "one", "uno", 1),
+		gitApply(t, "file1", multiHunkOrig, patches[0]))
+	require.Equal(t, strings.Replace(multiHunkOrig, "nine", "nueve", 1),
+		gitApply(t, "file1", multiHunkOrig, patches[1]))
+}
+
+func TestToUnified(t *testing.T) {
+	diff, err := Parse(`diff --git a/file1 b/file1
+index 504d2a1..50ccec3 100644
+--- a/file1
++++ b/file1
+@@ -1,10 +1,10 @@ section
+ one
+ two
+ three
+-four
++cuatro
+ five
+ six
+ seven
+ eight
+-nine
++nueve
+ ten
+`)
+	require.NoError(t, err)
+
+	header := "diff --git a/file1 b/file1\nindex 504d2a1..50ccec3 100644\n--- a/file1\n+++ b/file1\n"
+	require.Equal(t, header+`@@ -3,3 +3,3 @@ section
+ three
+-four
++cuatro
+ five
+@@ -8,3 +8,3 @@ section
+ eight
+-nine
++nueve
+ ten
+`, diff.ToUnified(1))
+
+	require.Equal(t, header+`@@ -4,1 +4,1 @@ section
+-four
++cuatro
+@@ -9,1 +9,1 @@ section
+-nine
++nueve
+`, diff.ToUnified(0))
+
+	orig := "one\ntwo\nthree\nfour\nfive\nsix\nseven\neight\nnine\nten\n"
+	want := "one\ntwo\nthree\ncuatro\nfive\nsix\nseven\neight\nnueve\nten\n"
+	require.Equal(t, want, gitApply(t, "file1", orig, diff.ToUnified(1)))
+	require.Equal(t, want, gitApply(t, "file1", orig, diff.ToUnified(3)))
+}
+
+func TestToUnifiedPureInsertion(t *testing.T) {
+	diff, err := Parse(`diff --git a/file1 b/file1
+index 504d2a1..50ccec3 100644
+--- a/file1
++++ b/file1
+@@ -1,4 +1,5 @@
+ one
+ two
++two and a half
+ three
+ four
+`)
+	require.NoError(t, err)
+	require.Equal(t, `diff --git a/file1 b/file1
+index 504d2a1..50ccec3 100644
+--- a/file1
++++ b/file1
+@@ -2,0 +3,1 @@
++two and a half
+`, diff.ToUnified(0))
+}
+
+func TestString(t *testing.T) {
+	// Modify-only diffs with explicit range lengths round-trip exactly.
+	diff, err := Parse(multiHunkDiff)
+	require.NoError(t, err)
+	require.Equal(t, multiHunkDiff, diff.String())
+	require.Equal(t, strings.Replace(strings.Replace(multiHunkOrig, "one", "uno", 1), "nine", "nueve", 1),
+		gitApply(t, "file1", multiHunkOrig, diff.String()))
+
+	for _, input := range []string{renameDiff, `diff --git a/bin/run b/bin/run
+old mode 100644
+new mode 100755
+diff --git a/file4 b/file4
+new file mode 100644
+index 0000000..57271b1
+--- /dev/null
++++ b/file4
+@@ -0,0 +1,1 @@
++added new file
+diff --git a/file2 b/file2
+deleted file mode 100644
+index c0dafd8..0000000
+--- a/file2
++++ /dev/null
+@@ -1,2 +0,0 @@
+-other
+-lines
+`} {
+		diff, err := Parse(input)
+		require.NoError(t, err)
+		require.Equal(t, input, diff.String())
+	}
+}
+
+func TestFormat(t *testing.T) {
+	input := `diff --git a/file1 b/file1
+index 504d2a1..50ccec3 100644
+--- a/file1
++++ b/file1
+@@ -1,2 +1,2 @@
+ one
+-two
+\ No newline at end of file
++dos
+\ No newline at end of file
+`
+	diff, err := Parse(input)
+	require.NoError(t, err)
+	require.Equal(t, input, diff.Format(FormatOptions{}))
+	require.Equal(t, input, diff.Format(FormatOptions{LineEnding: "\n"}))
+
+	crlf := diff.Format(FormatOptions{LineEnding: "\r\n"})
+	require.Equal(t, strings.Replace(input, "\n", "\r\n", -1), crlf)
+
+	again, err := Parse(crlf)
+	require.NoError(t, err)
+	lines := again.Files[0].Chunks[0].WholeRange.Lines
+	require.Len(t, lines, 3)
+	require.Equal(t, "one", lines[0].Content)
+	require.False(t, lines[0].NoNewline)
+	require.Equal(t, "two", lines[1].Content)
+	require.True(t, lines[1].NoNewline)
+	require.Equal(t, "dos", lines[2].Content)
+	require.True(t, lines[2].NoNewline)
+}
@@ -0,0 +1,117 @@
+// Copyright (c) 2015 Jesse Meek <https://github.com/waigani>
+// This program is Free Software see LICENSE file for details.
+
+package diffparser
+
+import (
+	"errors"
+	"io/ioutil"
+	"mime"
+	"net/mail"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Patch is a commit as written by "git format-patch": its metadata, the
+// commit message and the diff.
+type Patch struct {
+	Commit  string // the sha from the "From <sha>" line
+	Author  string // the "From:" header, such as "Jane Doe <jane@example.com>"
+	Date    time.Time
+	Subject string // without its "[PATCH]" prefix
+	Body    string // the rest of the commit message
+
+	*Diff
+}
+
+// reMboxFrom matches the "From <sha> <date>" line that starts each patch
+// "git format-patch" writes.
+var reMboxFrom = regexp.MustCompile(`^From ([0-9a-f]{40}) `)
+
+// reSubjectPrefix matches the "[PATCH]", "[PATCH 2/3]" or "[PATCH v2]"
+// prefix "git format-patch" adds to the subject.
+var reSubjectPrefix = regexp.MustCompile(`^\[[^]]*PATCH[^]]*\]\s*`)
+
+// ParsePatch parses a patch written by "git format-patch". The diff is the
+// mail's body, so its UnparsedPrefix holds the commit message and the
+// diffstat.
+func ParsePatch(s string) (*Patch, error) {
+	firstLine := s
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		firstLine, s = s[:i], s[i+1:]
+	}
+	m := reMboxFrom.FindStringSubmatch(firstLine)
+	if m == nil {
+		return nil, errors.New("patch does not start with a \"From <sha>\" line")
+	}
+	patch := &Patch{Commit: m[1]}
+
+	msg, err := mail.ReadMessage(strings.NewReader(s))
+	if err != nil {
+		return nil, err
+	}
+	dec := new(mime.WordDecoder)
+	if patch.Author, err = dec.DecodeHeader(msg.Header.Get("From")); err != nil {
+		return nil, err
+	}
+	if patch.Subject, err = dec.DecodeHeader(msg.Header.Get("Subject")); err != nil {
+		return nil, err
+	}
+	patch.Subject = reSubjectPrefix.ReplaceAllString(patch.Subject, "")
+	if msg.Header.Get("Date") != "" {
+		if patch.Date, err = msg.Header.Date(); err != nil {
+			return nil, err
+		}
+	}
+
+	body, err := ioutil.ReadAll(msg.Body)
+	if err != nil {
+		return nil, err
+	}
+	if patch.Diff, err = Parse(string(body)); err != nil {
+		return nil, err
+	}
+	patch.Body = commitMessage(patch.Diff.UnparsedPrefix)
+	return patch, nil
+}
+
+// commitMessage returns the commit message from the text before a patch's
+// diff: everything up to the "---" line that precedes the diffstat.
+func commitMessage(prefix string) string {
+	if strings.HasPrefix(prefix, "---\n") {
+		return ""
+	}
+	if i := strings.Index(prefix, "\n---\n"); i >= 0 {
+		prefix = prefix[:i]
+	}
+	return strings.TrimSpace(prefix)
+}
+
+// ParseMbox parses the patches of concatenated "git format-patch" output,
+// such as a mailbox written by "git format-patch --stdout".
+func ParseMbox(s string) ([]*Patch, error) {
+	var patches []*Patch
+	var start int
+	lines := strings.SplitAfter(s, "\n")
+	offset := 0
+	for i, l := range lines {
+		if i > 0 && reMboxFrom.MatchString(l) {
+			patch, err := ParsePatch(s[start:offset])
+			if err != nil {
+				return nil, err
+			}
+			patches = append(patches, patch)
+			start = offset
+		}
+		offset += len(l)
+	}
+	if strings.TrimSpace(s[start:]) == "" {
+		return patches, nil
+	}
+	patch, err := ParsePatch(s[start:])
+	if err != nil {
+		return nil, err
+	}
+	return append(patches, patch), nil
+}
@@ -0,0 +1,88 @@
+// Copyright (c) 2015 Jesse Meek <https://github.com/waigani>
+// This program is Free Software see LICENSE file for details.
+
+package diffparser
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+const formatPatch = `From 5ce542458522ec5659e07ad07b0c1700a836b213 Mon Sep 17 00:00:00 2001
+From: =?UTF-8?q?Ren=C3=A9=20Doe?= <rene@example.com>
+Date: Wed, 14 Oct 2026 07:57:50 +0000
+Subject: [PATCH 1/2] Change the second line of file1 so that the subject
+ is folded
+
+The body explains
+the change.
+---
+ file1 | 2 +-
+ 1 file changed, 1 insertion(+), 1 deletion(-)
+
+diff --git a/file1 b/file1
+index 4cb29ea..02dc020 100644
+--- a/file1
++++ b/file1
+@@ -1,3 +1,3 @@
+ one
+-two
++dos
+ three
+-- 
+2.39.5
+
+`
+
+func TestParsePatch(t *testing.T) {
+	patch, err := ParsePatch(formatPatch)
+	require.NoError(t, err)
+	require.Equal(t, "5ce542458522ec5659e07ad07b0c1700a836b213", patch.Commit)
+	require.Equal(t, "René Doe <rene@example.com>", patch.Author)
+	require.True(t, time.Date(2026, 10, 14, 7, 57, 50, 0, time.UTC).Equal(patch.Date))
+	require.Equal(t, "Change the second line of file1 so that the subject is folded", patch.Subject)
+	require.Equal(t, "The body explains\nthe change.", patch.Body)
+
+	require.Len(t, patch.Files, 1)
+	require.Equal(t, "file1", patch.Files[0].NewName)
+	require.Len(t, patch.Files[0].Chunks[0].WholeRange.Lines, 4)
+
+	_, err = ParsePatch("diff --git a/file1 b/file1\n")
+	require.EqualError(t, err, `patch does not start with a "From <sha>" line`)
+}
+
+func TestParseMbox(t *testing.T) {
+	second := `From 9550552a6b7d0f2c9e1c2b4f3a5d6e7f8091a2b3 Mon Sep 17 00:00:00 2001
+From: Jane Doe <jane@example.com>
+Date: Thu, 15 Oct 2026 08:00:00 +0000
+Subject: [PATCH 2/2] Add file2
+
+---
+ file2 | 1 +
+ 1 file changed, 1 insertion(+)
+
+diff --git a/file2 b/file2
+new file mode 100644
+index 0000000..50ccec3
+--- /dev/null
++++ b/file2
+@@ -0,0 +1 @@
++new
+-- 
+2.39.5
+`
+	patches, err := ParseMbox(formatPatch + second)
+	require.NoError(t, err)
+	require.Len(t, patches, 2)
+	require.Equal(t, "5ce542458522ec5659e07ad07b0c1700a836b213", patches[0].Commit)
+	require.Len(t, patches[0].Files, 1)
+	require.Equal(t, "Add file2", patches[1].Subject)
+	require.Empty(t, patches[1].Body)
+	require.Equal(t, New, patches[1].Files[0].Mode)
+
+	patches, err = ParseMbox("")
+	require.NoError(t, err)
+	require.Empty(t, patches)
+}
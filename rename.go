@@ -0,0 +1,112 @@
+// Copyright (c) 2015 Jesse Meek <https://github.com/waigani>
+// This program is Free Software see LICENSE file for details.
+
+package diffparser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ExpandRenames returns a copy of d in which each Renamed file is replaced
+// by the deletion of its original name followed by a New file of its new
+// name, as a diff without rename detection lists them. Those remove and add
+// whole files, so files, a map of path to content as ApplyTo takes, must
+// hold the content of each renamed file before the change. The other files
+// are copied as they are, and Raw is not kept, as it no longer describes
+// the diff. It returns an error if a renamed file is missing from files or
+// its hunks don't apply to it.
+func (d *Diff) ExpandRenames(files map[string]string) (*Diff, error) {
+	c := d.clone()
+	c.Raw = ""
+	renamed := c.Files
+	c.Files = make([]*DiffFile, 0, len(renamed))
+	for _, f := range renamed {
+		if f.Mode != Renamed {
+			c.Files = append(c.Files, f)
+			continue
+		}
+		orig, ok := files[f.OrigName]
+		if !ok {
+			return nil, fmt.Errorf("file %q does not exist", f.OrigName)
+		}
+		new, err := f.Apply(orig)
+		if err != nil {
+			return nil, err
+		}
+		deleted, err := f.expandedSide(Deleted, orig)
+		if err != nil {
+			return nil, err
+		}
+		added, err := f.expandedSide(New, new)
+		if err != nil {
+			return nil, err
+		}
+		c.Files = append(c.Files, deleted, added)
+	}
+	return c, nil
+}
+
+// expandedSide returns the Deleted file of the original name, or the New
+// file of the new name, that stands for one side of the renamed file f,
+// removing or adding content as a whole.
+func (f *DiffFile) expandedSide(mode FileMode, content string) (*DiffFile, error) {
+	s := *f
+	s.Mode = mode
+	s.Similarity = 0
+	s.OldMode, s.NewMode = "", ""
+	s.BinaryPatch = nil
+	s.SummaryAdditions, s.SummaryDeletions = 0, 0
+	s.ExtraHeaders = nil
+	for _, h := range f.ExtraHeaders {
+		if !strings.HasPrefix(h, "similarity index ") && !strings.HasPrefix(h, "dissimilarity index ") {
+			s.ExtraHeaders = append(s.ExtraHeaders, h)
+		}
+	}
+
+	fileMode := f.IndexMode
+	var whole *Diff
+	var err error
+	if mode == Deleted {
+		if f.OldMode != "" {
+			fileMode = f.OldMode
+		}
+		s.NewName = ""
+		if s.OrigSHA != "" {
+			s.NewSHA = strings.Repeat("0", len(s.OrigSHA))
+		}
+		s.DiffHeader = "diff --git " + quoteName("a/"+f.OrigName) + " " + quoteName("b/"+f.OrigName)
+		if fileMode != "" {
+			s.ExtraHeaders = append(s.ExtraHeaders, "deleted file mode "+fileMode)
+		}
+		whole, err = GenerateUnifiedWithContext(content, "", f.OrigName, 0)
+	} else {
+		if f.NewMode != "" {
+			fileMode = f.NewMode
+		}
+		s.OrigName = ""
+		if s.NewSHA != "" {
+			s.OrigSHA = strings.Repeat("0", len(s.NewSHA))
+		}
+		s.DiffHeader = "diff --git " + quoteName("a/"+f.NewName) + " " + quoteName("b/"+f.NewName)
+		if fileMode != "" {
+			s.ExtraHeaders = append(s.ExtraHeaders, "new file mode "+fileMode)
+		}
+		whole, err = GenerateUnifiedWithContext("", content, f.NewName, 0)
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.IndexMode = ""
+	s.Chunks = nil
+	if len(whole.Files) > 0 {
+		s.Chunks = whole.Files[0].Chunks
+	}
+	return &s, nil
+}
+
+// RenamedWithEdits reports whether the file is renamed and its content
+// changed too, so that it has hunks as well as a new name.
+func (f *DiffFile) RenamedWithEdits() bool {
+	return f.Mode == Renamed && len(f.Chunks) > 0
+}
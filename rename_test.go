@@ -0,0 +1,108 @@
+// Copyright (c) 2015 Jesse Meek <https://github.com/waigani>
+// This program is Free Software see LICENSE file for details.
+
+package diffparser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const expandRenamesDiff = `diff --git a/old.txt b/new.txt
+similarity index 90%
+rename from old.txt
+rename to new.txt
+index 504d2a1..50ccec3 100644
+--- a/old.txt
++++ b/new.txt
+@@ -1 +1 @@
+-one
++uno
+diff --git a/file1 b/file1
+index 504d2a1..50ccec3 100644
+--- a/file1
++++ b/file1
+@@ -1 +1 @@
+-two
++dos
+`
+
+func TestExpandRenames(t *testing.T) {
+	diff, err := Parse(expandRenamesDiff)
+	require.NoError(t, err)
+	files := map[string]string{"old.txt": "one\n", "file1": "two\n"}
+
+	expanded, err := diff.ExpandRenames(files)
+	require.NoError(t, err)
+	require.Len(t, expanded.Files, 3)
+	require.Empty(t, expanded.Raw)
+	require.NoError(t, expanded.Validate())
+
+	deleted, added, other := expanded.Files[0], expanded.Files[1], expanded.Files[2]
+	require.Equal(t, Deleted, deleted.Mode)
+	require.Equal(t, "old.txt", deleted.OrigName)
+	require.Equal(t, "", deleted.NewName)
+	require.Equal(t, []string{"deleted file mode 100644"}, deleted.ExtraHeaders)
+	require.Equal(t, "diff --git a/old.txt b/old.txt", deleted.DiffHeader)
+	require.Equal(t, `diff --git a/old.txt b/old.txt
+deleted file mode 100644
+index 504d2a1..0000000
+--- a/old.txt
++++ /dev/null
+@@ -1,1 +0,0 @@
+-one
+`, deleted.String())
+
+	require.Equal(t, New, added.Mode)
+	require.Equal(t, "", added.OrigName)
+	require.Equal(t, "new.txt", added.NewName)
+	require.Equal(t, []string{"new file mode 100644"}, added.ExtraHeaders)
+	require.Equal(t, "0000000", added.OrigSHA)
+	require.Zero(t, added.Similarity)
+	require.Equal(t, `diff --git a/new.txt b/new.txt
+new file mode 100644
+index 0000000..50ccec3
+--- /dev/null
++++ b/new.txt
+@@ -0,0 +1,1 @@
++uno
+`, added.String())
+
+	require.Equal(t, Modified, other.Mode)
+	require.Equal(t, "file1", other.NewName)
+
+	// The expanded diff changes the files as the diff does.
+	want, err := diff.ApplyTo(files)
+	require.NoError(t, err)
+	got, err := expanded.ApplyTo(files)
+	require.NoError(t, err)
+	require.Equal(t, want, got)
+	require.Equal(t, want, gitApplyFiles(t, files, expanded.String()))
+
+	// The diff expanded from is left as it was.
+	require.Len(t, diff.Files, 2)
+	require.Equal(t, Renamed, diff.Files[0].Mode)
+	require.Equal(t, 90, diff.Files[0].Similarity)
+	require.Len(t, diff.Files[0].Chunks, 1)
+	require.False(t, diff.Files[1] == other)
+
+	_, err = diff.ExpandRenames(map[string]string{"file1": "two\n"})
+	require.EqualError(t, err, `file "old.txt" does not exist`)
+	_, err = diff.ExpandRenames(map[string]string{"old.txt": "three\n"})
+	require.EqualError(t, err, `hunk 1 of "new.txt": line 1 does not match "one"`)
+}
+
+func TestRenamedWithEdits(t *testing.T) {
+	diff, err := Parse(expandRenamesDiff + `diff --git a/a.txt b/b.txt
+similarity index 100%
+rename from a.txt
+rename to b.txt
+`)
+	require.NoError(t, err)
+	require.Len(t, diff.Files, 3)
+	require.True(t, diff.Files[0].RenamedWithEdits())
+	require.False(t, diff.Files[1].RenamedWithEdits())
+	require.Equal(t, Renamed, diff.Files[2].Mode)
+	require.False(t, diff.Files[2].RenamedWithEdits())
+}
@@ -0,0 +1,189 @@
+// Copyright (c) 2015 Jesse Meek <https://github.com/waigani>
+// This program is Free Software see LICENSE file for details.
+
+package diffparser
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Reverse returns a new diff that undoes d: applying it to the files d
+// produces gives back the files d was made from. Raw is not kept, as it no
+// longer describes the diff.
+func (d *Diff) Reverse() *Diff {
+	r := *d
+	r.Raw = ""
+	r.Files = make([]*DiffFile, len(d.Files))
+	for i, f := range d.Files {
+		r.Files[i] = f.Reverse()
+	}
+	return &r
+}
+
+// Reverse returns a new file diff that undoes f. Added lines become removed
+// lines and removed lines added, the original and new sides swap, and New
+// and Deleted files swap modes. A Copied file reverses to the deletion of
+// the copy. As the diff holds only the copy's changes from its source, not
+// its whole content, the deletion has no hunks: it is consistent, but git
+// can only apply it to an empty file.
+func (f *DiffFile) Reverse() *DiffFile {
+	r := f.clone()
+	r.OrigName, r.NewName = f.NewName, f.OrigName
+	r.OrigSHA, r.NewSHA = f.NewSHA, f.OrigSHA
+	r.OldMode, r.NewMode = f.NewMode, f.OldMode
+	r.OrigSubmoduleCommit, r.NewSubmoduleCommit = f.NewSubmoduleCommit, f.OrigSubmoduleCommit
+	r.SummaryAdditions, r.SummaryDeletions = f.SummaryDeletions, f.SummaryAdditions
+	switch f.Mode {
+	case New:
+		r.Mode = Deleted
+	case Deleted:
+		r.Mode = New
+	case Copied:
+		r.Mode = Deleted
+		r.NewName = ""
+		r.OldMode, r.NewMode = "", ""
+		r.Similarity = 0
+		r.Chunks = nil
+		r.BinaryPatch = nil
+		r.SummaryAdditions, r.SummaryDeletions = 0, 0
+		if r.OrigSHA != "" {
+			r.NewSHA = strings.Repeat("0", len(r.OrigSHA))
+		}
+		mode := f.IndexMode
+		if f.NewMode != "" {
+			mode = f.NewMode
+		}
+		r.IndexMode = ""
+		var headers []string
+		for _, h := range f.ExtraHeaders {
+			if !strings.HasPrefix(h, "similarity index ") && !strings.HasPrefix(h, "dissimilarity index ") {
+				headers = append(headers, h)
+			}
+		}
+		if mode != "" {
+			headers = append(headers, "deleted file mode "+mode)
+		}
+		r.ExtraHeaders = headers
+		return r
+	}
+
+	if f.ExtraHeaders != nil {
+		r.ExtraHeaders = make([]string, len(f.ExtraHeaders))
+		for i, h := range f.ExtraHeaders {
+			switch {
+			case strings.HasPrefix(h, "new file mode "):
+				h = "deleted file mode " + strings.TrimPrefix(h, "new file mode ")
+			case strings.HasPrefix(h, "deleted file mode "):
+				h = "new file mode " + strings.TrimPrefix(h, "deleted file mode ")
+			}
+			r.ExtraHeaders[i] = h
+		}
+	}
+
+	// A binary patch's second block undoes its first.
+	if blocks := binaryBlocks(f.BinaryPatch); len(blocks) == 2 {
+		r.BinaryPatch = append(append([]string(nil), blocks[1]...), blocks[0]...)
+	}
+
+	for _, h := range r.Chunks {
+		h.reverse()
+	}
+	return r
+}
+
+// binaryBlocks splits the lines of a binary patch into its blocks, each
+// with the blank line that ends it.
+func binaryBlocks(lines []string) [][]string {
+	var blocks [][]string
+	start := 0
+	for i, l := range lines {
+		if l == "" {
+			blocks = append(blocks, lines[start:i+1])
+			start = i + 1
+		}
+	}
+	return blocks
+}
+
+// reverse swaps the sides of the chunk in place.
+func (hunk *DiffChunk) reverse() {
+	// Unchanged lines of WholeRange are shared with NewRange, which becomes
+	// the original side, so they are replaced by those of OrigRange.
+	var unchanged []*DiffLine
+	for _, l := range hunk.OrigRange.Lines {
+		if l.Mode == Unchanged {
+			unchanged = append(unchanged, l)
+		}
+	}
+	whole := make([]*DiffLine, 0, len(hunk.WholeRange.Lines))
+	for _, l := range hunk.WholeRange.Lines {
+		if l.Mode == Unchanged && len(unchanged) > 0 {
+			l, unchanged = unchanged[0], unchanged[1:]
+		}
+		whole = append(whole, l)
+	}
+
+	seen := make(map[*DiffLine]bool)
+	for _, lines := range [][]*DiffLine{hunk.OrigRange.Lines, hunk.NewRange.Lines} {
+		for _, l := range lines {
+			if seen[l] {
+				continue
+			}
+			seen[l] = true
+			switch l.Mode {
+			case Added:
+				l.Mode = Removed
+			case Removed:
+				l.Mode = Added
+			}
+			l.OrigNumber, l.NewNumber = l.NewNumber, l.OrigNumber
+		}
+	}
+
+	// Removed lines go before the added lines of the same change, as in
+	// diffs git makes. The lines of a change keep their positions.
+	for i := 0; i < len(whole); {
+		if whole[i].Mode == Unchanged {
+			i++
+			continue
+		}
+		j := i
+		var removed, added []*DiffLine
+		for ; j < len(whole) && whole[j].Mode != Unchanged; j++ {
+			if whole[j].Mode == Removed {
+				removed = append(removed, whole[j])
+			} else {
+				added = append(added, whole[j])
+			}
+		}
+		positions := make([]int, 0, j-i)
+		for _, l := range whole[i:j] {
+			positions = append(positions, l.Position)
+		}
+		copy(whole[i:], append(removed, added...))
+		for k, l := range whole[i:j] {
+			l.Position = positions[k]
+		}
+		i = j
+	}
+
+	hunk.OrigRange, hunk.NewRange = hunk.NewRange, hunk.OrigRange
+	hunk.WholeRange.Lines = whole
+	hunk.RawHeader = reverseHunkHeader(hunk.RawHeader)
+}
+
+// reverseHunkHeader swaps the ranges of a "@@" header line, keeping the way
+// each is written. It returns "" for a line that isn't a header.
+func reverseHunkHeader(line string) string {
+	m := reHunkRanges.FindStringSubmatchIndex(line)
+	if m == nil {
+		return ""
+	}
+	orig, new := line[m[2]:m[3]], line[m[4]:m[5]]
+	return line[:m[2]] + new + line[m[3]:m[4]] + orig + line[m[5]:]
+}
+
+// reHunkRanges matches the ranges of a "@@" header line, without their
+// "-" and "+" signs.
+var reHunkRanges = regexp.MustCompile(`^@@ -(\d+(?:,\d+)?) \+(\d+(?:,\d+)?) @@`)
@@ -0,0 +1,111 @@
+// Copyright (c) 2015 Jesse Meek <https://github.com/waigani>
+// This program is Free Software see LICENSE file for details.
+
+package diffparser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReverse(t *testing.T) {
+	diff, err := Parse(multiHunkDiff)
+	require.NoError(t, err)
+	reversed := diff.Reverse()
+
+	content, err := diff.Files[0].Apply(multiHunkOrig)
+	require.NoError(t, err)
+	orig, err := reversed.Files[0].Apply(content)
+	require.NoError(t, err)
+	require.Equal(t, multiHunkOrig, orig)
+
+	require.Equal(t, `diff --git a/file1 b/file1
+index 50ccec3..504d2a1 100644
+--- a/file1
++++ b/file1
+@@ -1,4 +1,4 @@ first
+-uno
++one
+ two
+ three
+ four
+@@ -7,4 +7,4 @@ second
+ seven
+ eight
+-nueve
++nine
+ ten
+`, reversed.String())
+	require.Equal(t, multiHunkOrig, gitApply(t, "file1", content, reversed.String()))
+
+	// The original diff is untouched.
+	require.Equal(t, Removed, diff.Files[0].Chunks[0].WholeRange.Lines[0].Mode)
+	require.Equal(t, "one", diff.Files[0].Chunks[0].WholeRange.Lines[0].Content)
+
+	// Reversing twice gives back the diff.
+	twice := reversed.Reverse()
+	twice.Raw = diff.Raw
+	require.Equal(t, diff, twice)
+}
+
+func TestReverseFiles(t *testing.T) {
+	diff := setup(t)
+	files := map[string]string{
+		"file1":   "some\nlines\nin\nfile1\n",
+		"file2":   "other\nlines\nin\nfile2\n",
+		"file3":   "still\nmore\nin\nfile3",
+		"symlink": "symlink-destination",
+	}
+
+	result, err := diff.ApplyTo(files)
+	require.NoError(t, err)
+	reverted, err := diff.Reverse().ApplyTo(result)
+	require.NoError(t, err)
+	require.Equal(t, files, reverted)
+
+	reversed := diff.Files[1].Reverse()
+	require.Equal(t, Deleted, diff.Files[1].Mode)
+	require.Equal(t, New, reversed.Mode)
+	require.Equal(t, "file2", reversed.NewName)
+	require.Empty(t, reversed.OrigName)
+	require.NoError(t, diff.Reverse().Validate())
+}
+
+func TestReverseCopy(t *testing.T) {
+	diff, err := Parse(`diff --git a/orig.txt b/copy.txt
+similarity index 75%
+copy from orig.txt
+copy to copy.txt
+index 504d2a1..50ccec3 100644
+--- a/orig.txt
++++ b/copy.txt
+@@ -1,2 +1,2 @@
+ one
+-two
++dos
+`)
+	require.NoError(t, err)
+
+	reversed := diff.Reverse()
+	require.NoError(t, reversed.Validate())
+	f := reversed.Files[0]
+	require.Equal(t, Deleted, f.Mode)
+	require.Equal(t, "copy.txt", f.OrigName)
+	require.Empty(t, f.NewName)
+	require.Empty(t, f.Chunks)
+	require.Equal(t, `diff --git a/copy.txt b/copy.txt
+deleted file mode 100644
+index 50ccec3..0000000
+`, reversed.String())
+
+	result, err := reversed.ApplyTo(map[string]string{"orig.txt": "one\ntwo\n", "copy.txt": "one\ndos\n"})
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"orig.txt": "one\ntwo\n"}, result)
+}
+
+func TestReverseHunkHeader(t *testing.T) {
+	require.Equal(t, "@@ -1,3 +2 @@ func main() {", reverseHunkHeader("@@ -2 +1,3 @@ func main() {"))
+	require.Equal(t, "@@ -0,0 +1,2 @@", reverseHunkHeader("@@ -1,2 +0,0 @@"))
+	require.Empty(t, reverseHunkHeader("not a header"))
+}
@@ -0,0 +1,81 @@
+// Copyright (c) 2015 Jesse Meek <https://github.com/waigani>
+// This program is Free Software see LICENSE file for details.
+
+package diffparser
+
+import (
+	"path"
+	"strings"
+)
+
+// Stats summarises the size of a diff.
+type Stats struct {
+	Files     int
+	Additions int
+	Deletions int
+}
+
+// Additions returns the number of lines added to the file.
+func (f *DiffFile) Additions() int {
+	return f.countLines(Added) + f.SummaryAdditions
+}
+
+// Deletions returns the number of lines removed from the file.
+func (f *DiffFile) Deletions() int {
+	return f.countLines(Removed) + f.SummaryDeletions
+}
+
+// Additions returns the number of lines added in the hunk.
+func (hunk *DiffChunk) Additions() int {
+	return hunk.countLines(Added)
+}
+
+// Deletions returns the number of lines removed in the hunk.
+func (hunk *DiffChunk) Deletions() int {
+	return hunk.countLines(Removed)
+}
+
+// Additions returns the number of lines added across all files.
+func (d *Diff) Additions() int {
+	var n int
+	for _, f := range d.Files {
+		n += f.Additions()
+	}
+	return n
+}
+
+// Deletions returns the number of lines removed across all files.
+func (d *Diff) Deletions() int {
+	var n int
+	for _, f := range d.Files {
+		n += f.Deletions()
+	}
+	return n
+}
+
+// Stats returns the number of files, additions and deletions in the diff.
+func (d *Diff) Stats() Stats {
+	return Stats{
+		Files:     len(d.Files),
+		Additions: d.Additions(),
+		Deletions: d.Deletions(),
+	}
+}
+
+// StatsByExtension returns the stats of the diff's files grouped by the
+// lower case extension of their names, as path.Ext returns it: ".go" for
+// "main.go" and "" for "Makefile". Files are named by their new name, or
+// for deleted files their original one. Binary files count as files with
+// no additions or deletions.
+func (d *Diff) StatsByExtension() map[string]Stats {
+	stats := make(map[string]Stats)
+	for _, f := range d.Files {
+		ext := strings.ToLower(path.Ext(f.name()))
+		s := stats[ext]
+		s.Files++
+		s.Additions += f.Additions()
+		s.Deletions += f.Deletions()
+		stats[ext] = s
+	}
+	return stats
+}
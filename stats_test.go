@@ -0,0 +1,88 @@
+// Copyright (c) 2015 Jesse Meek <https://github.com/waigani>
+// This program is Free Software see LICENSE file for details.
+
+package diffparser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStats(t *testing.T) {
+	diff := setup(t)
+
+	for i, expected := range []struct {
+		additions, deletions int
+	}{
+		{1, 1}, {0, 4}, {0, 4}, {1, 0}, {4, 0}, {0, 1},
+	} {
+		require.Equal(t, expected.additions, diff.Files[i].Additions(), "file %d", i)
+		require.Equal(t, expected.deletions, diff.Files[i].Deletions(), "file %d", i)
+	}
+
+	require.Equal(t, 6, diff.Additions())
+	require.Equal(t, 10, diff.Deletions())
+	require.Equal(t, Stats{Files: 6, Additions: 6, Deletions: 10}, diff.Stats())
+}
+
+func TestChunkStats(t *testing.T) {
+	diff, err := Parse(`diff --git a/file1 b/file1
+index 504d2a1..50ccec3 100644
+--- a/file1
++++ b/file1
+@@ -1,4 +1,3 @@
+-one
+-two
++uno
+ three
+ four
+@@ -7,2 +6,4 @@
+ seven
++eight
++nine
+ ten
+`)
+	require.NoError(t, err)
+
+	chunks := diff.Files[0].Chunks
+	require.Equal(t, 1, chunks[0].Additions())
+	require.Equal(t, 2, chunks[0].Deletions())
+	require.Equal(t, 2, chunks[1].Additions())
+	require.Equal(t, 0, chunks[1].Deletions())
+}
+
+func TestStatsByExtension(t *testing.T) {
+	diff, err := Parse(`diff --git a/main.go b/main.go
+index 504d2a1..50ccec3 100644
+--- a/main.go
++++ b/main.go
+@@ -1 +1,2 @@
+ package main
++// Main.
+diff --git a/util.GO b/util.GO
+deleted file mode 100644
+index 504d2a1..0000000
+--- a/util.GO
++++ /dev/null
+@@ -1,2 +0,0 @@
+-package main
+-
+diff --git a/Makefile b/Makefile
+index 504d2a1..50ccec3 100644
+--- a/Makefile
++++ b/Makefile
+@@ -1 +1 @@
+-all:
++all: build
+diff --git a/logo.png b/logo.png
+index 504d2a1..50ccec3 100644
+Binary files a/logo.png and b/logo.png differ
+`)
+	require.NoError(t, err)
+	require.Equal(t, map[string]Stats{
+		".go":  {Files: 2, Additions: 1, Deletions: 2},
+		"":     {Files: 1, Additions: 1, Deletions: 1},
+		".png": {Files: 1},
+	}, diff.StatsByExtension())
+}
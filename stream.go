@@ -0,0 +1,66 @@
+// Copyright (c) 2015 Jesse Meek <https://github.com/waigani>
+// This program is Free Software see LICENSE file for details.
+
+package diffparser
+
+import (
+	"bufio"
+	"io"
+)
+
+// StreamParser reads a diff from an io.Reader one file at a time.
+type StreamParser struct {
+	r    *bufio.Reader
+	p    *parser
+	next int   // index in the parsed files of the next file to return
+	done bool  // the whole diff has been read
+	err  error // the error that stopped the parse, if any
+}
+
+// NewStreamParser returns a StreamParser reading from r.
+func NewStreamParser(r io.Reader) *StreamParser {
+	return &StreamParser{r: bufio.NewReader(r), p: newParser(ParseOptions{})}
+}
+
+// Next reads and parses the next file of the diff. It returns io.EOF when
+// there are no more files. Text before the first file header is skipped.
+// Files start where Parse starts them, at "diff " lines and at the first
+// lines of plain and Subversion diffs, and their line positions and parse
+// errors are the same as with Parse. A file is returned once the next one
+// starts or the diff ends.
+func (p *StreamParser) Next() (*DiffFile, error) {
+	for {
+		files := p.p.diff.Files
+		if p.next < len(files)-1 || p.done && p.next < len(files) {
+			f := files[p.next]
+			// The parser is done with the file, so it need not be kept.
+			files[p.next] = nil
+			p.next++
+			f.finish()
+			return f, nil
+		}
+		if p.done {
+			return nil, io.EOF
+		}
+		if p.err != nil {
+			return nil, p.err
+		}
+
+		line, err := p.r.ReadString('\n')
+		if len(line) > 0 {
+			if p.err = p.p.readLine(line); p.err != nil {
+				return nil, p.err
+			}
+		}
+		switch {
+		case err == io.EOF:
+			if p.err = p.p.end(); p.err != nil {
+				return nil, p.err
+			}
+			p.done = true
+		case err != nil:
+			p.err = err
+			return nil, err
+		}
+	}
+}
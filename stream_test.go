@@ -0,0 +1,92 @@
+// Copyright (c) 2015 Jesse Meek <https://github.com/waigani>
+// This program is Free Software see LICENSE file for details.
+
+package diffparser
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStreamParser(t *testing.T) {
+	byt, err := ioutil.ReadFile("example.diff")
+	require.NoError(t, err)
+	expected := setup(t)
+
+	for name, r := range map[string]io.Reader{
+		"whole":    bytes.NewReader(byt),
+		"one byte": iotest.OneByteReader(bytes.NewReader(append([]byte("preamble\n"), byt...))),
+		"half":     iotest.HalfReader(bytes.NewReader(byt)),
+	} {
+		p := NewStreamParser(r)
+		for i, want := range expected.Files {
+			file, err := p.Next()
+			require.NoError(t, err, name)
+			require.Equal(t, want, file, "%s: file %d", name, i)
+		}
+		_, err := p.Next()
+		require.Equal(t, io.EOF, err, name)
+		_, err = p.Next()
+		require.Equal(t, io.EOF, err, name)
+	}
+
+	for name, input := range map[string]string{
+		"plain": `--- file1.orig	2020-01-01 00:00:00.000000000 +0000
++++ file1	2020-01-02 00:00:00.000000000 +0000
+@@ -1,2 +1,2 @@
+-one
++uno
+ two
+--- /dev/null	1970-01-01 00:00:00.000000000 +0000
++++ b/dir/file2	2020-01-02 00:00:00.000000000 +0000
+@@ -0,0 +1 @@
++new
+`,
+		"svn": `Index: src/main.c
+===================================================================
+--- src/main.c	(revision 41)
++++ src/main.c	(working copy)
+@@ -1,2 +1,2 @@
+-one
++uno
+ two
+Index: docs/new.txt
+===================================================================
+--- docs/new.txt	(nonexistent)
++++ docs/new.txt	(working copy)
+@@ -0,0 +1 @@
++new
+`,
+	} {
+		expected, err := Parse(input)
+		require.NoError(t, err, name)
+		require.Len(t, expected.Files, 2, name)
+
+		p := NewStreamParser(iotest.OneByteReader(strings.NewReader(input)))
+		for i, want := range expected.Files {
+			file, err := p.Next()
+			require.NoError(t, err, name)
+			require.Equal(t, want, file, "%s: file %d", name, i)
+		}
+		_, err = p.Next()
+		require.Equal(t, io.EOF, err, name)
+	}
+}
+
+func TestStreamParserErrors(t *testing.T) {
+	p := NewStreamParser(strings.NewReader("diff --git a/f b/f\n--- a/f\n+++ b/f\n@@ -1 +1 @@\n*bad\n"))
+	_, err := p.Next()
+	require.EqualError(t, err, `line 5: could not parse line mode: "*bad"`)
+	_, err = p.Next()
+	require.Error(t, err)
+
+	p = NewStreamParser(iotest.TimeoutReader(iotest.OneByteReader(strings.NewReader("diff --git a/f b/f\n"))))
+	_, err = p.Next()
+	require.Equal(t, iotest.ErrTimeout, err)
+}
@@ -0,0 +1,47 @@
+// Copyright (c) 2015 Jesse Meek <https://github.com/waigani>
+// This program is Free Software see LICENSE file for details.
+
+package diffparser
+
+import (
+	"strings"
+)
+
+// Suggestion expresses the hunk as a GitHub "suggestion" block: the lines
+// startLine to endLine (inclusive) are to be replaced by replacement, which
+// holds the added lines separated by newlines. The lines are numbered in
+// the original file, as a suggestion is placed on the lines it replaces.
+//
+// Only a hunk that replaces one contiguous block of removed lines with one
+// contiguous block of added lines (possibly empty) can be expressed. For
+// any other hunk, such as a pure insertion or one with several separate
+// changes, startLine and endLine are 0 and replacement is empty.
+func (hunk *DiffChunk) Suggestion() (startLine, endLine int, replacement string) {
+	lines := hunk.WholeRange.Lines
+	first, last := -1, -1
+	for i, l := range lines {
+		if l.Mode != Unchanged {
+			if first < 0 {
+				first = i
+			}
+			last = i
+		}
+	}
+	if first < 0 || lines[first].Mode != Removed {
+		return 0, 0, ""
+	}
+
+	var added []string
+	for _, l := range lines[first : last+1] {
+		switch {
+		case l.Mode == Removed && len(added) == 0:
+			endLine = l.Number
+		case l.Mode == Added:
+			added = append(added, l.Content)
+		default:
+			// Context between changes, or removals after additions.
+			return 0, 0, ""
+		}
+	}
+	return lines[first].Number, endLine, strings.Join(added, "\n")
+}
@@ -0,0 +1,44 @@
+// Copyright (c) 2015 Jesse Meek <https://github.com/waigani>
+// This program is Free Software see LICENSE file for details.
+
+package diffparser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSuggestion(t *testing.T) {
+	header := "diff --git a/file1 b/file1\nindex 504d2a1..50ccec3 100644\n--- a/file1\n+++ b/file1\n"
+	for _, test := range []struct {
+		hunk        string
+		start, end  int
+		replacement string
+	}{{
+		hunk:        "@@ -10,4 +10,5 @@\n ten\n-eleven\n-twelve\n+once\n+doce\n+trece\n thirteen\n",
+		start:       11,
+		end:         12,
+		replacement: "once\ndoce\ntrece",
+	}, {
+		hunk:  "@@ -10,2 +10,1 @@\n ten\n-eleven\n",
+		start: 11,
+		end:   11,
+	}, {
+		// Pure insertion.
+		hunk: "@@ -10,1 +10,2 @@\n ten\n+diez\n",
+	}, {
+		// Two separate changes.
+		hunk: "@@ -10,3 +10,3 @@\n-ten\n+diez\n eleven\n-twelve\n+doce\n",
+	}, {
+		// Removal after addition.
+		hunk: "@@ -10,2 +10,2 @@\n-ten\n+diez\n-eleven\n+once\n",
+	}} {
+		diff, err := Parse(header + test.hunk)
+		require.NoError(t, err)
+		start, end, replacement := diff.Files[0].Chunks[0].Suggestion()
+		require.Equal(t, test.start, start, test.hunk)
+		require.Equal(t, test.end, end, test.hunk)
+		require.Equal(t, test.replacement, replacement, test.hunk)
+	}
+}
@@ -0,0 +1,140 @@
+// Copyright (c) 2015 Jesse Meek <https://github.com/waigani>
+// This program is Free Software see LICENSE file for details.
+
+package diffparser
+
+import (
+	"strings"
+)
+
+// ParseNumstat parses the output of "git diff --numstat", a line of
+// "<additions>\t<deletions>\t<path>" for each file, into a Diff of files
+// without chunks whose Additions and Deletions are the listed counts.
+// Binary files, listed with "-" counts, are marked IsBinary. A rename is
+// listed as "<old> => <new>", or with the changed part of the path in
+// braces as "dir/{<old> => <new>}/file", and sets the Renamed mode. Other
+// files are Modified, as numstat does not tell new and deleted files apart.
+func ParseNumstat(s string) (*Diff, error) {
+	diff := &Diff{Raw: s}
+	for i, l := range strings.Split(s, "\n") {
+		l = strings.TrimSuffix(l, "\r")
+		if l == "" {
+			continue
+		}
+		fields := strings.SplitN(l, "\t", 3)
+		if len(fields) < 3 || fields[2] == "" {
+			return nil, &ParseError{Line: i, Text: l, Reason: "could not parse numstat line"}
+		}
+
+		f := &DiffFile{Mode: Modified}
+		if fields[0] == "-" && fields[1] == "-" {
+			f.IsBinary = true
+		} else {
+			var err error
+			if f.SummaryAdditions, err = atoi32(fields[0]); err != nil || f.SummaryAdditions < 0 {
+				return nil, &ParseError{Line: i, Text: l, Reason: "could not parse numstat line"}
+			}
+			if f.SummaryDeletions, err = atoi32(fields[1]); err != nil || f.SummaryDeletions < 0 {
+				return nil, &ParseError{Line: i, Text: l, Reason: "could not parse numstat line"}
+			}
+		}
+		f.OrigName, f.NewName = renameNames(unquoteName(fields[2]))
+		if f.OrigName != f.NewName {
+			f.Mode = Renamed
+		}
+		diff.Files = append(diff.Files, f)
+	}
+	return diff, nil
+}
+
+// ParseNameStatus parses the output of "git diff --name-status", a line of
+// "<status>\t<path>" for each file, into a Diff of files without chunks.
+// The status letters are A (New), C (Copied), D (Deleted), M (Modified),
+// R (Renamed) and T (a type change, taken as Modified). Copies and renames
+// give a similarity score after the letter and both paths, as in
+// "R100\t<old>\t<new>", and set the file's Similarity.
+func ParseNameStatus(s string) (*Diff, error) {
+	diff := &Diff{Raw: s}
+	for i, l := range strings.Split(s, "\n") {
+		l = strings.TrimSuffix(l, "\r")
+		if l == "" {
+			continue
+		}
+		fields := strings.Split(l, "\t")
+		if fields[0] == "" {
+			return nil, &ParseError{Line: i, Text: l, Reason: "could not parse name-status line"}
+		}
+		mode, ok := nameStatusMode(fields[0][0])
+		if !ok {
+			return nil, &ParseError{Line: i, Text: l, Reason: "unknown file status"}
+		}
+		paths := 1
+		if mode == Renamed || mode == Copied {
+			paths = 2
+		}
+		if len(fields) != paths+1 {
+			return nil, &ParseError{Line: i, Text: l, Reason: "could not parse name-status line"}
+		}
+
+		f := &DiffFile{Mode: mode}
+		switch mode {
+		case New:
+			f.NewName = unquoteName(fields[1])
+		case Deleted:
+			f.OrigName = unquoteName(fields[1])
+		case Renamed, Copied:
+			f.OrigName, f.NewName = unquoteName(fields[1]), unquoteName(fields[2])
+			if score := fields[0][1:]; score != "" {
+				n, err := atoi32(score)
+				if err != nil || n < 0 || n > 100 {
+					return nil, &ParseError{Line: i, Text: l, Reason: "could not parse similarity score"}
+				}
+				f.Similarity = n
+			}
+		default:
+			f.OrigName, f.NewName = unquoteName(fields[1]), unquoteName(fields[1])
+		}
+		diff.Files = append(diff.Files, f)
+	}
+	return diff, nil
+}
+
+// nameStatusMode returns the file mode of a "git diff --name-status"
+// status letter, and false for letters without one.
+func nameStatusMode(letter byte) (FileMode, bool) {
+	if letter == 'T' {
+		return Modified, true
+	}
+	for _, m := range []FileMode{New, Deleted, Modified, Renamed, Copied} {
+		if m.filterLetter() == letter {
+			return m, true
+		}
+	}
+	return 0, false
+}
+
+// renameNames returns the original and new names of a path as git's
+// diffstat prints it, "<old> => <new>" or "<prefix>{<old> => <new>}<suffix>"
+// for a rename, and just the path otherwise.
+func renameNames(path string) (orig, new string) {
+	i := strings.Index(path, " => ")
+	if i < 0 {
+		return path, path
+	}
+	open := strings.LastIndexByte(path[:i], '{')
+	end := strings.IndexByte(path[i:], '}')
+	if open < 0 || end < 0 {
+		return path[:i], path[i+len(" => "):]
+	}
+	end += i
+	prefix, suffix := path[:open], path[end+1:]
+	join := func(middle string) string {
+		// An empty side, as in "dir/{ => sub}/file", leaves a doubled
+		// or leading separator.
+		if middle == "" {
+			return prefix + strings.TrimPrefix(suffix, "/")
+		}
+		return prefix + middle + suffix
+	}
+	return join(path[open+1 : i]), join(path[i+len(" => ") : end])
+}
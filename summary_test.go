@@ -0,0 +1,90 @@
+// Copyright (c) 2015 Jesse Meek <https://github.com/waigani>
+// This program is Free Software see LICENSE file for details.
+
+package diffparser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseNumstat(t *testing.T) {
+	diff, err := ParseNumstat("3\t1\tmain.go\n" +
+		"-\t-\timage.png\n" +
+		"0\t0\told.txt => new.txt\n" +
+		"2\t2\tsrc/{lib => pkg}/util.go\n" +
+		"1\t0\tsrc/{ => sub}/x.go\n" +
+		"4\t0\t\"tab\\there\"\n")
+	require.NoError(t, err)
+	require.Len(t, diff.Files, 6)
+
+	for i, want := range []struct {
+		orig, new   string
+		mode        FileMode
+		binary      bool
+		added, rmed int
+	}{
+		{"main.go", "main.go", Modified, false, 3, 1},
+		{"image.png", "image.png", Modified, true, 0, 0},
+		{"old.txt", "new.txt", Renamed, false, 0, 0},
+		{"src/lib/util.go", "src/pkg/util.go", Renamed, false, 2, 2},
+		{"src/x.go", "src/sub/x.go", Renamed, false, 1, 0},
+		{"tab\there", "tab\there", Modified, false, 4, 0},
+	} {
+		f := diff.Files[i]
+		require.Equal(t, want.orig, f.OrigName, "file %d", i)
+		require.Equal(t, want.new, f.NewName, "file %d", i)
+		require.Equal(t, want.mode, f.Mode, "file %d", i)
+		require.Equal(t, want.binary, f.IsBinary, "file %d", i)
+		require.Equal(t, want.added, f.Additions(), "file %d", i)
+		require.Equal(t, want.rmed, f.Deletions(), "file %d", i)
+		require.Empty(t, f.Chunks)
+	}
+	require.Equal(t, Stats{Files: 6, Additions: 10, Deletions: 3}, diff.Stats())
+	require.Equal(t, 1, diff.Reverse().Files[0].Additions())
+
+	_, err = ParseNumstat("3\t1\tmain.go\nx\t1\tother.go\n")
+	require.EqualError(t, err, `line 2: could not parse numstat line: "x\t1\tother.go"`)
+	_, err = ParseNumstat("3 1 main.go\n")
+	require.Error(t, err)
+}
+
+func TestParseNameStatus(t *testing.T) {
+	diff, err := ParseNameStatus("M\tmain.go\n" +
+		"A\tnew.go\n" +
+		"D\told.go\n" +
+		"R100\tfrom.go\tto.go\n" +
+		"C75\tsrc.go\tcopy.go\n" +
+		"T\tlink\n")
+	require.NoError(t, err)
+	require.Len(t, diff.Files, 6)
+
+	for i, want := range []struct {
+		orig, new  string
+		mode       FileMode
+		similarity int
+	}{
+		{"main.go", "main.go", Modified, 0},
+		{"", "new.go", New, 0},
+		{"old.go", "", Deleted, 0},
+		{"from.go", "to.go", Renamed, 100},
+		{"src.go", "copy.go", Copied, 75},
+		{"link", "link", Modified, 0},
+	} {
+		f := diff.Files[i]
+		require.Equal(t, want.orig, f.OrigName, "file %d", i)
+		require.Equal(t, want.new, f.NewName, "file %d", i)
+		require.Equal(t, want.mode, f.Mode, "file %d", i)
+		require.Equal(t, want.similarity, f.Similarity, "file %d", i)
+		require.Empty(t, f.Chunks)
+	}
+	require.Equal(t, []string{"to.go"}, fileNames(diff.DiffFilter("R").Files))
+
+	for _, bad := range []string{"Q\tfile", "R100\tfrom.go", "M\ta\tb", "R1x\ta\tb", "\tfoo"} {
+		_, err := ParseNameStatus(bad + "\n")
+		require.Error(t, err, bad)
+		_, ok := err.(*ParseError)
+		require.True(t, ok, bad)
+	}
+}
@@ -0,0 +1,72 @@
+// Copyright (c) 2015 Jesse Meek <https://github.com/waigani>
+// This program is Free Software see LICENSE file for details.
+
+package diffparser
+
+import (
+	"fmt"
+)
+
+// Validate checks the parsed diff for internal consistency and returns the
+// first problem found, or nil if the diff is consistent.
+func (d *Diff) Validate() error {
+	for _, f := range d.Files {
+		if err := f.validateDevNull(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateDevNull checks that New and Deleted files agree with the
+// /dev/null side of the diff: a New file has no original name and only
+// added lines, a Deleted file has no new name and only removed lines.
+func (f *DiffFile) validateDevNull() error {
+	switch f.Mode {
+	case New:
+		if f.OrigName != "" {
+			return fmt.Errorf("new file %q has original name %q", f.NewName, f.OrigName)
+		}
+		if f.countLines(Added) != f.countAll() {
+			return fmt.Errorf("new file %q has removed or unchanged lines", f.NewName)
+		}
+	case Deleted:
+		if f.NewName != "" {
+			return fmt.Errorf("deleted file %q has new name %q", f.OrigName, f.NewName)
+		}
+		if f.countLines(Removed) != f.countAll() {
+			return fmt.Errorf("deleted file %q has added or unchanged lines", f.OrigName)
+		}
+	}
+	return nil
+}
+
+// countLines returns the number of lines in the file with the given mode.
+func (f *DiffFile) countLines(mode DiffLineMode) int {
+	var n int
+	for _, h := range f.Chunks {
+		n += h.countLines(mode)
+	}
+	return n
+}
+
+// countLines returns the number of lines of the hunk with the given mode.
+// Each line is counted once, from WholeRange.
+func (hunk *DiffChunk) countLines(mode DiffLineMode) int {
+	var n int
+	for _, l := range hunk.WholeRange.Lines {
+		if l.Mode == mode {
+			n++
+		}
+	}
+	return n
+}
+
+// countAll returns the number of lines in the file's hunks.
+func (f *DiffFile) countAll() int {
+	var n int
+	for _, h := range f.Chunks {
+		n += len(h.WholeRange.Lines)
+	}
+	return n
+}
@@ -0,0 +1,56 @@
+// Copyright (c) 2015 Jesse Meek <https://github.com/waigani>
+// This program is Free Software see LICENSE file for details.
+
+package diffparser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	diff := setup(t)
+	require.NoError(t, diff.Validate())
+}
+
+func TestValidateDevNull(t *testing.T) {
+	for _, test := range []struct {
+		diff string
+		err  string
+	}{
+		{
+			diff: `diff --git a/file4 b/file4
+new file mode 100644
+index 0000000..57271b1
+--- /dev/null
++++ b/file4
+@@ -0,0 +1,2 @@
++added
+-removed
+`,
+			err: `new file "file4" has removed or unchanged lines`,
+		}, {
+			diff: `diff --git a/file2 b/file2
+deleted file mode 100644
+index c0dafd8..0000000
+--- a/file2
++++ /dev/null
+@@ -1,2 +0,0 @@
+-other
+ lines
+`,
+			err: `deleted file "file2" has added or unchanged lines`,
+		},
+	} {
+		diff, err := Parse(test.diff)
+		require.NoError(t, err)
+		require.EqualError(t, diff.Validate(), test.err)
+	}
+
+	diff := &Diff{Files: []*DiffFile{{Mode: New, OrigName: "old", NewName: "new"}}}
+	require.EqualError(t, diff.Validate(), `new file "new" has original name "old"`)
+
+	diff = &Diff{Files: []*DiffFile{{Mode: Deleted, OrigName: "old", NewName: "new"}}}
+	require.EqualError(t, diff.Validate(), `deleted file "old" has new name "new"`)
+}